@@ -16,7 +16,17 @@ import (
 	"go.uber.org/zap"
 )
 
-const defaultRepoDir = "_Repos"
+const (
+	defaultRepoDir = "_Repos"
+	// deletedArchivePrefix holds archives of repos deleted upstream that are waiting out their retention
+	deletedArchivePrefix = "_deleted/"
+	// metaArchiveSuffix marks the issue and pull request exports that sit next to repo archives
+	metaArchiveSuffix = ".meta.tar.gz"
+	// releasesDirSuffix marks the directories of downloaded release assets, which may be tarballs themselves
+	releasesDirSuffix = ".releases/"
+	// migrationsArchivePrefix holds organization migration archives, which span many repos
+	migrationsArchivePrefix = "_migrations/"
+)
 
 func Start(ctx context.Context, interval time.Duration) {
 	go func() {
@@ -133,6 +143,11 @@ func collectSnapshot(ctx context.Context, repoDir string) (*models.RepoAnalytics
 		return nil, err
 	}
 
+	ownerCount, deletedArchiveCount, err := collectArchiveLayout(ctx, repoDir)
+	if err != nil {
+		return nil, err
+	}
+
 	snapshot := &models.RepoAnalyticsSnapshot{
 		HeadCommit:              headCommit,
 		HeadCommitMessage:       metaParts[2],
@@ -150,11 +165,46 @@ func collectSnapshot(ctx context.Context, repoDir string) (*models.RepoAnalytics
 		AvgArchiveSizeBytes:     avgArchiveSize,
 		LargestArchivePath:      largestArchivePath,
 		LargestArchiveSizeBytes: largestArchiveSize,
+		OwnerCount:              ownerCount,
+		DeletedArchiveCount:     deletedArchiveCount,
 	}
 
 	return snapshot, nil
 }
 
+// collectArchiveLayout understands the <owner>/<repo>.tar.gz layout: it counts distinct owner
+// directories holding live archives and the archives parked under _deleted/
+func collectArchiveLayout(ctx context.Context, repoDir string) (ownerCount int, deletedArchiveCount int, err error) {
+	output, err := runGit(ctx, repoDir, "ls-tree", "-r", "--name-only", "--full-name", "HEAD")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	owners := make(map[string]bool)
+	for _, path := range strings.Split(output, "\n") {
+		path = strings.TrimSpace(path)
+		if !isRepoArchive(path) {
+			continue
+		}
+		if strings.HasPrefix(path, deletedArchivePrefix) {
+			deletedArchiveCount++
+			continue
+		}
+		if owner, _, ok := strings.Cut(path, "/"); ok {
+			owners[owner] = true
+		}
+	}
+
+	return len(owners), deletedArchiveCount, nil
+}
+
+func isRepoArchive(path string) bool {
+	return strings.HasSuffix(path, ".tar.gz") &&
+		!strings.HasSuffix(path, metaArchiveSuffix) &&
+		!strings.Contains(path, releasesDirSuffix) &&
+		!strings.HasPrefix(path, migrationsArchivePrefix)
+}
+
 func collectTreeStats(ctx context.Context, repoDir string) (trackedFiles int, totalBlobSize int64, avgBlobSize int64, largestBlobPath string, largestBlobSize int64, archiveCount int, totalArchiveSize int64, avgArchiveSize int64, largestArchivePath string, largestArchiveSize int64, err error) {
 	output, err := runGit(ctx, repoDir, "ls-tree", "-r", "-l", "--full-name", "HEAD")
 	if err != nil {
@@ -196,7 +246,7 @@ func collectTreeStats(ctx context.Context, repoDir string) (trackedFiles int, to
 			largestBlobPath = path
 		}
 
-		if strings.HasSuffix(path, ".tar.gz") {
+		if isRepoArchive(path) && !strings.HasPrefix(path, deletedArchivePrefix) {
 			archiveCount++
 			totalArchiveSize += size
 			if size > largestArchiveSize {
@@ -235,12 +285,14 @@ func persistSnapshot(ctx context.Context, snapshot *models.RepoAnalyticsSnapshot
 			run_id, head_commit, head_commit_message, head_commit_at,
 			total_commits, branch_count, tag_count, tracked_files,
 			total_blob_size_bytes, avg_blob_size_bytes, largest_blob_path, largest_blob_size_bytes,
-			archive_count, total_archive_size_bytes, avg_archive_size_bytes, largest_archive_path, largest_archive_size_bytes
+			archive_count, total_archive_size_bytes, avg_archive_size_bytes, largest_archive_path, largest_archive_size_bytes,
+			owner_count, deleted_archive_count
 		) VALUES (
 			$1, $2, $3, $4,
 			$5, $6, $7, $8,
 			$9, $10, $11, $12,
-			$13, $14, $15, $16, $17
+			$13, $14, $15, $16, $17,
+			$18, $19
 		)`
 
 	_, err := db.Pool.Exec(ctx, query,
@@ -248,6 +300,7 @@ func persistSnapshot(ctx context.Context, snapshot *models.RepoAnalyticsSnapshot
 		snapshot.TotalCommits, snapshot.BranchCount, snapshot.TagCount, snapshot.TrackedFiles,
 		snapshot.TotalBlobSizeBytes, snapshot.AvgBlobSizeBytes, snapshot.LargestBlobPath, snapshot.LargestBlobSizeBytes,
 		snapshot.ArchiveCount, snapshot.TotalArchiveSizeBytes, snapshot.AvgArchiveSizeBytes, snapshot.LargestArchivePath, snapshot.LargestArchiveSizeBytes,
+		snapshot.OwnerCount, snapshot.DeletedArchiveCount,
 	)
 	return err
 }
@@ -24,6 +24,8 @@ CREATE TABLE IF NOT EXISTS backup_results (
     error_message TEXT DEFAULT '',
     created_at TIMESTAMPTZ DEFAULT NOW()
 );
+-- Refs that triggered the backup: +ref created, ~ref moved, -ref deleted
+ALTER TABLE backup_results ADD COLUMN IF NOT EXISTS changed_refs TEXT[] NOT NULL DEFAULT '{}';
 
 -- Execution logs from worker
 CREATE TABLE IF NOT EXISTS execution_logs (
@@ -59,6 +61,8 @@ CREATE TABLE IF NOT EXISTS analytics_snapshots (
     largest_archive_path TEXT DEFAULT '',
     largest_archive_size_bytes BIGINT DEFAULT 0
 );
+ALTER TABLE analytics_snapshots ADD COLUMN IF NOT EXISTS owner_count INT DEFAULT 0;
+ALTER TABLE analytics_snapshots ADD COLUMN IF NOT EXISTS deleted_archive_count INT DEFAULT 0;
 CREATE INDEX IF NOT EXISTS idx_analytics_snapshots_time ON analytics_snapshots(captured_at);
 CREATE INDEX IF NOT EXISTS idx_analytics_snapshots_run ON analytics_snapshots(run_id);
 
@@ -56,7 +56,7 @@ func GetBackupRun(c *fiber.Ctx) error {
 
 	// Get results for this run
 	rows, err := db.Pool.Query(context.Background(),
-		`SELECT id, run_id, repo_full_name, status, commit_hash, archive_size_bytes, duration_ms, error_message, created_at
+		`SELECT id, run_id, repo_full_name, status, commit_hash, changed_refs, archive_size_bytes, duration_ms, error_message, created_at
 		 FROM backup_results WHERE run_id = $1 ORDER BY created_at`, id)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
@@ -66,7 +66,7 @@ func GetBackupRun(c *fiber.Ctx) error {
 	var results []models.BackupResult
 	for rows.Next() {
 		var br models.BackupResult
-		if err := rows.Scan(&br.ID, &br.RunID, &br.RepoFullName, &br.Status, &br.CommitHash,
+		if err := rows.Scan(&br.ID, &br.RunID, &br.RepoFullName, &br.Status, &br.CommitHash, &br.ChangedRefs,
 			&br.ArchiveSizeBytes, &br.DurationMs, &br.ErrorMessage, &br.CreatedAt); err != nil {
 			continue
 		}
@@ -21,6 +21,7 @@ type BackupResult struct {
 	RepoFullName     string    `json:"repo_full_name"`
 	Status           string    `json:"status"`
 	CommitHash       string    `json:"commit_hash"`
+	ChangedRefs      []string  `json:"changed_refs"`
 	ArchiveSizeBytes int64     `json:"archive_size_bytes"`
 	DurationMs       int64     `json:"duration_ms"`
 	ErrorMessage     string    `json:"error_message"`
@@ -82,6 +83,8 @@ type RepoAnalyticsSnapshot struct {
 	AvgArchiveSizeBytes     int64      `json:"avg_archive_size_bytes"`
 	LargestArchivePath      string     `json:"largest_archive_path"`
 	LargestArchiveSizeBytes int64      `json:"largest_archive_size_bytes"`
+	OwnerCount              int        `json:"owner_count"`
+	DeletedArchiveCount     int        `json:"deleted_archive_count"`
 }
 
 // API response types
@@ -1,12 +1,27 @@
 package config
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
 	"github.com/MishraShardendu22/github-backup/model"
 	"github.com/MishraShardendu22/github-backup/util"
 	"github.com/joho/godotenv"
 	"go.uber.org/zap"
 )
 
+const (
+	DefaultGitHubAPIURL = "https://api.github.com"
+	DefaultGitLabAPIURL = "https://gitlab.com/api/v4"
+	// DefaultGitCloneHost is the SSH host alias the backup machine's ~/.ssh/config maps to the project key
+	DefaultGitCloneHost = "git@github.com-project"
+)
+
 func LoadEnv() {
 	currEnv := "development"
 
@@ -20,21 +35,430 @@ func LoadEnv() {
 }
 
 func LoadConfig() *model.ConfigModel {
-	return &model.ConfigModel{
-		OrgAccount:          util.GetEnv("ORG_ACCOUNT", ""),
-		MainAccount:         util.GetEnv("MAIN_ACCOUNT", ""),
-		DBPath:              util.GetEnv("DB_PATH", "./app.db"),
-		ProjectAccount:      util.GetEnv("PROJECT_ACCOUNT", ""),
-		BackupRepoPath:      util.GetEnv("BACKUP_REPO_PATH", ""),
-		GitHubTokenPrivate:  util.GetEnv("GITHUB_TOKEN_PRIVATE", ""),
-		GitHubTokenPersonal: util.GetEnv("GITHUB_TOKEN_PERSONAL", ""),
+	cfg := &model.ConfigModel{
+		OrgAccount:           util.GetEnv("ORG_ACCOUNT", ""),
+		MainAccount:          util.GetEnv("MAIN_ACCOUNT", ""),
+		DBPath:               util.GetEnv("DB_PATH", "./app.db"),
+		ProjectAccount:       util.GetEnv("PROJECT_ACCOUNT", ""),
+		BackupRepoPath:       util.GetEnv("BACKUP_REPO_PATH", ""),
+		GitHubTokenPrivate:   util.GetEnv("GITHUB_TOKEN_PRIVATE", ""),
+		GitHubTokenPersonal:  util.GetEnv("GITHUB_TOKEN_PERSONAL", ""),
+		GitHubAPIURL:         util.GetEnv("GITHUB_API_URL", DefaultGitHubAPIURL),
+		GitCloneHost:         util.GetEnv("GIT_CLONE_HOST", DefaultGitCloneHost),
+		DeletionMaxCount:     util.GetEnvInt("DELETION_MAX_COUNT", 10),
+		DeletionMaxPercent:   util.GetEnvFloat("DELETION_MAX_PERCENT", 20),
+		DeletedRetentionDays: util.GetEnvInt("DELETED_RETENTION_DAYS", 30),
+		PreflightMinFreeGB:   util.GetEnvInt("PREFLIGHT_MIN_FREE_GB", 5),
+		BackupWikis:          util.GetEnvBool("BACKUP_WIKIS", true),
+		ExportIssues:         util.GetEnvBool("EXPORT_ISSUES", false),
+		BackupReleases:       util.GetEnvBool("BACKUP_RELEASES", false),
+		ReleaseAssetPolicy:   loadAssetPolicy(),
+		SnapshotGovernance:   util.GetEnvBool("SNAPSHOT_GOVERNANCE", false),
+		MigrationPollSeconds: util.GetEnvInt("MIGRATION_POLL_SECONDS", 30),
+		MigrationTimeout:     util.GetEnvInt("MIGRATION_TIMEOUT_MINUTES", 180),
+		BackupMode:           loadBackupMode(),
+		BackupModeRules:      loadBackupModeRules(),
+		MirrorIncrementals:   util.GetEnvInt("MIRROR_MAX_INCREMENTALS", 10),
+		MirrorFullDays:       util.GetEnvInt("MIRROR_FULL_BUNDLE_DAYS", 30),
+		MirrorCacheDir:       util.GetEnv("MIRROR_CACHE_DIR", "_MirrorCache"),
+		MirrorCacheMaxGB:     util.GetEnvInt("MIRROR_CACHE_MAX_GB", 20),
+		APIHashCheck:         util.GetEnvBool("API_HASH_CHECK", true),
+		Filters:              LoadFilterRules(),
+	}
+	cfg.GitHubApp = loadGitHubApp()
+	cfg.Sources = LoadSources(cfg)
+
+	return cfg
+}
+
+func LoadFilterRules() model.FilterRules {
+	rules := model.FilterRules{
+		SkipForks:        util.GetEnvBool("FILTER_SKIP_FORKS", false),
+		SkipArchived:     util.GetEnvBool("FILTER_SKIP_ARCHIVED", false),
+		SkipDisabled:     util.GetEnvBool("FILTER_SKIP_DISABLED", false),
+		IncludeOwners:    util.GetEnvList("FILTER_INCLUDE_OWNERS"),
+		ExcludeOwners:    util.GetEnvList("FILTER_EXCLUDE_OWNERS"),
+		IncludeNames:     util.GetEnvList("FILTER_INCLUDE_NAMES"),
+		ExcludeNames:     util.GetEnvList("FILTER_EXCLUDE_NAMES"),
+		Visibilities:     util.GetEnvList("FILTER_VISIBILITY"),
+		IncludeLanguages: util.GetEnvList("FILTER_INCLUDE_LANGUAGES"),
+		ExcludeLanguages: util.GetEnvList("FILTER_EXCLUDE_LANGUAGES"),
+		IncludeTopics:    util.GetEnvList("FILTER_INCLUDE_TOPICS"),
+		ExcludeTopics:    util.GetEnvList("FILTER_EXCLUDE_TOPICS"),
+	}
+	if err := validateNamePatterns(rules.IncludeNames); err != nil {
+		util.ErrorHandler(fmt.Errorf("FILTER_INCLUDE_NAMES: %w", err))
+	}
+	if err := validateNamePatterns(rules.ExcludeNames); err != nil {
+		util.ErrorHandler(fmt.Errorf("FILTER_EXCLUDE_NAMES: %w", err))
+	}
+	return rules
+}
+
+// validateNamePatterns rejects re: patterns that do not compile. Dropping them instead would quietly
+// widen an include list, or empty it and back up everything.
+func validateNamePatterns(patterns []string) error {
+	for _, raw := range patterns {
+		if expr, ok := strings.CutPrefix(raw, "re:"); ok {
+			if _, err := regexp.Compile("(?i)" + expr); err != nil {
+				return fmt.Errorf("invalid pattern %q: %w", raw, err)
+			}
+		}
+	}
+	return nil
+}
+
+// loadAssetPolicy reads RELEASE_ASSET_POLICY; a typo must not silently turn into a different policy
+func loadAssetPolicy() string {
+	policy := strings.ToLower(strings.TrimSpace(util.GetEnv("RELEASE_ASSET_POLICY", model.AssetPolicySplit)))
+	switch policy {
+	case model.AssetPolicyStore, model.AssetPolicySplit, model.AssetPolicyRecord:
+		return policy
+	default:
+		util.ErrorHandler(fmt.Errorf("RELEASE_ASSET_POLICY %q: expected %s, %s or %s",
+			policy, model.AssetPolicyStore, model.AssetPolicySplit, model.AssetPolicyRecord))
+		return ""
+	}
+}
+
+func loadBackupMode() string {
+	mode := strings.ToLower(strings.TrimSpace(util.GetEnv("BACKUP_MODE", model.BackupModeSnapshot)))
+	if err := validateBackupMode(mode); err != nil {
+		util.ErrorHandler(fmt.Errorf("BACKUP_MODE: %w", err))
+	}
+	return mode
+}
+
+// loadBackupModeRules reads BACKUP_MODE_RULES, a comma separated list of <pattern>=<mode> entries
+func loadBackupModeRules() []model.BackupModeRule {
+	var rules []model.BackupModeRule
+	for _, entry := range util.GetEnvList("BACKUP_MODE_RULES") {
+		pattern, mode, ok := strings.Cut(entry, "=")
+		mode = strings.ToLower(strings.TrimSpace(mode))
+		if !ok || strings.TrimSpace(pattern) == "" {
+			util.ErrorHandler(fmt.Errorf("BACKUP_MODE_RULES entry %q: expected <pattern>=<mode>", entry))
+			continue
+		}
+		if err := validateBackupMode(mode); err != nil {
+			util.ErrorHandler(fmt.Errorf("BACKUP_MODE_RULES entry %q: %w", entry, err))
+			continue
+		}
+		if err := validateNamePatterns([]string{strings.TrimSpace(pattern)}); err != nil {
+			util.ErrorHandler(fmt.Errorf("BACKUP_MODE_RULES entry %q: %w", entry, err))
+			continue
+		}
+		rules = append(rules, model.BackupModeRule{Pattern: strings.TrimSpace(pattern), Mode: mode})
+	}
+	return rules
+}
+
+func validateBackupMode(mode string) error {
+	switch mode {
+	case model.BackupModeSnapshot, model.BackupModeMirror, model.BackupModeBoth:
+		return nil
+	default:
+		return fmt.Errorf("unknown backup mode %q (expected %s, %s or %s)",
+			mode, model.BackupModeSnapshot, model.BackupModeMirror, model.BackupModeBoth)
+	}
+}
+
+// LoadSources reads the source list from the JSON file named by SOURCES_FILE. Without it the
+// legacy single-account variables are turned into the original org, public and private sources.
+func LoadSources(cfg *model.ConfigModel) []model.Source {
+	path := util.GetEnv("SOURCES_FILE", "")
+	if path == "" {
+		return legacySources(cfg)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		util.ErrorHandler(fmt.Errorf("read SOURCES_FILE %s: %w", path, err))
+	}
+
+	var sources []model.Source
+	if err := json.Unmarshal(data, &sources); err != nil {
+		util.ErrorHandler(fmt.Errorf("parse SOURCES_FILE %s: %w", path, err))
+	}
+
+	seen := make(map[string]bool, len(sources))
+	for i := range sources {
+		source := &sources[i]
+		if err := normalizeSource(source, cfg); err != nil {
+			util.ErrorHandler(fmt.Errorf("SOURCES_FILE %s, source %d: %w", path, i+1, err))
+		}
+		if seen[source.Name] {
+			util.ErrorHandler(fmt.Errorf("SOURCES_FILE %s: duplicate source name %q", path, source.Name))
+		}
+		seen[source.Name] = true
+	}
+
+	return sources
+}
+
+// loadGitHubApp reads the GITHUB_APP_* variables; nil means static tokens are used
+func loadGitHubApp() *model.GitHubApp {
+	appID, _ := strconv.ParseInt(util.GetEnv("GITHUB_APP_ID", ""), 10, 64)
+	if appID == 0 {
+		return nil
+	}
+
+	installationID, _ := strconv.ParseInt(util.GetEnv("GITHUB_APP_INSTALLATION_ID", ""), 10, 64)
+	app := &model.GitHubApp{
+		AppID:          appID,
+		InstallationID: installationID,
+		PrivateKeyFile: util.GetEnv("GITHUB_APP_PRIVATE_KEY_FILE", ""),
+	}
+	if os.Getenv("GITHUB_APP_PRIVATE_KEY") != "" {
+		app.PrivateKeyEnv = "GITHUB_APP_PRIVATE_KEY"
+	}
+
+	return app
+}
+
+func legacySources(cfg *model.ConfigModel) []model.Source {
+	var sources []model.Source
+	if cfg.GitHubApp != nil {
+		// An app installation already sees every repo it was granted, so it replaces the token sources
+		sources = append(sources, model.Source{Name: "app", Kind: model.SourceKindInstallation, App: cfg.GitHubApp})
+	} else {
+		if cfg.OrgAccount != "" {
+			sources = append(sources, model.Source{Name: "org", Kind: model.SourceKindOrg, Account: cfg.OrgAccount, Token: cfg.GitHubTokenPersonal, Type: "all"})
+		}
+		if cfg.ProjectAccount != "" {
+			sources = append(sources, model.Source{Name: "public", Kind: model.SourceKindUser, Account: cfg.ProjectAccount, Token: cfg.GitHubTokenPersonal, Type: "public"})
+		}
+		if cfg.GitHubTokenPrivate != "" {
+			sources = append(sources, model.Source{Name: "private", Kind: model.SourceKindAuthenticatedUser, Token: cfg.GitHubTokenPrivate, Type: "private"})
+		}
+	}
+
+	if util.GetEnvBool("BACKUP_STARRED", false) {
+		starred := model.Source{Name: "starred", Kind: model.SourceKindStarred, Token: cfg.GitHubTokenPrivate}
+		if starred.Token == "" {
+			starred.Token = cfg.GitHubTokenPersonal
+		}
+		if starred.Token == "" {
+			starred.Account = cfg.ProjectAccount
+		}
+		sources = append(sources, starred)
+	}
+
+	for _, account := range util.GetEnvList("GIST_USERS") {
+		sources = append(sources, model.Source{Name: "gists:" + account, Kind: model.SourceKindGists, Account: account, Token: cfg.GitHubTokenPersonal})
+	}
+
+	if urls := util.GetEnvList("EXTRA_REMOTES"); len(urls) > 0 {
+		extra := model.Source{Name: "extra", Provider: model.ProviderGit}
+		for _, remoteURL := range urls {
+			extra.Remotes = append(extra.Remotes, model.Remote{URL: remoteURL})
+		}
+		sources = append(sources, extra)
 	}
+
+	for i := range sources {
+		if err := normalizeSource(&sources[i], cfg); err != nil {
+			util.ErrorHandler(fmt.Errorf("source %s: %w", sources[i].Name, err))
+		}
+	}
+
+	return sources
+}
+
+// normalizeSource validates a configured source and fills in its defaults
+func normalizeSource(source *model.Source, cfg *model.ConfigModel) error {
+	if source.Filters != nil {
+		if err := validateNamePatterns(source.Filters.IncludeNames); err != nil {
+			return fmt.Errorf("filters.include_names: %w", err)
+		}
+		if err := validateNamePatterns(source.Filters.ExcludeNames); err != nil {
+			return fmt.Errorf("filters.exclude_names: %w", err)
+		}
+	}
+	if source.Mode != "" {
+		source.Mode = strings.ToLower(source.Mode)
+		if err := validateBackupMode(source.Mode); err != nil {
+			return err
+		}
+	}
+
+	switch source.Provider {
+	case "":
+		source.Provider = model.ProviderGitHub
+	case model.ProviderGitHub, model.ProviderGitLab, model.ProviderGitea:
+	case model.ProviderGit:
+		return normalizeRemotesSource(source)
+	default:
+		return fmt.Errorf("unknown provider %q (expected github, gitlab, gitea or git)", source.Provider)
+	}
+
+	switch source.Kind {
+	case model.SourceKindOrg, model.SourceKindUser:
+		if source.Account == "" {
+			return fmt.Errorf("kind %q needs an account", source.Kind)
+		}
+	case model.SourceKindAuthenticatedUser:
+		if source.App != nil {
+			return fmt.Errorf("kind %q cannot use app authentication; use kind %q", source.Kind, model.SourceKindInstallation)
+		}
+	case model.SourceKindInstallation:
+		if source.App == nil {
+			return fmt.Errorf("kind %q needs app authentication", source.Kind)
+		}
+	case model.SourceKindStarred:
+		if source.App != nil && source.Account == "" {
+			return fmt.Errorf("kind %q without an account lists the token owner's stars, which an app installation has none of", source.Kind)
+		}
+	case model.SourceKindGists:
+		if source.Provider != model.ProviderGitHub {
+			return fmt.Errorf("kind %q is only supported for the github provider", source.Kind)
+		}
+		if source.App != nil {
+			return fmt.Errorf("kind %q cannot use app authentication; app installations cannot list gists", source.Kind)
+		}
+	default:
+		return fmt.Errorf("unknown kind %q (expected org, user, authenticated-user, installation, starred or gists)", source.Kind)
+	}
+
+	if source.App != nil {
+		if err := validateGitHubApp(source); err != nil {
+			return err
+		}
+	}
+
+	if source.TokenEnv != "" {
+		source.Token = os.Getenv(source.TokenEnv)
+		if source.Token == "" {
+			return fmt.Errorf("token_env %s is empty", source.TokenEnv)
+		}
+	}
+	if source.Kind == model.SourceKindAuthenticatedUser && source.Token == "" {
+		return fmt.Errorf("kind %q needs a token", source.Kind)
+	}
+	if (source.Kind == model.SourceKindStarred || source.Kind == model.SourceKindGists) && source.Account == "" && source.Token == "" && source.App == nil {
+		return fmt.Errorf("kind %q needs an account or a token", source.Kind)
+	}
+
+	if source.APIURL == "" {
+		switch source.Provider {
+		case model.ProviderGitHub:
+			source.APIURL = cfg.GitHubAPIURL
+		case model.ProviderGitLab:
+			source.APIURL = DefaultGitLabAPIURL
+		default:
+			return fmt.Errorf("provider %q needs an api_url", source.Provider)
+		}
+	}
+	source.APIURL = strings.TrimRight(source.APIURL, "/")
+	// App installation tokens only work over HTTPS, so app sources clone from the web host
+	if source.CloneHost == "" && source.App != nil {
+		source.CloneHost = appCloneHost(source.APIURL)
+	}
+	// Other forges report a usable SSH URL per repo, so only GitHub falls back to the shared clone host
+	if source.CloneHost == "" && source.Provider == model.ProviderGitHub {
+		source.CloneHost = cfg.GitCloneHost
+	}
+	source.Namespace = strings.Trim(source.Namespace, "/")
+
+	if source.Type == "" {
+		source.Type = "owner"
+		if source.Kind == model.SourceKindOrg {
+			source.Type = "all"
+		}
+	}
+	if source.Name == "" {
+		source.Name = source.Provider + ":" + source.Kind
+		if source.Account != "" {
+			source.Name = source.Provider + ":" + source.Account
+		}
+	}
+
+	return nil
+}
+
+// normalizeRemotesSource validates a list of plain git remotes. A remote without a name is tracked as
+// <host>/<path>, so remotes on different hosts never share an archive.
+func normalizeRemotesSource(source *model.Source) error {
+	if len(source.Remotes) == 0 {
+		return fmt.Errorf("provider %q needs remotes", source.Provider)
+	}
+	source.Kind = model.SourceKindRemotes
+	source.Namespace = strings.Trim(source.Namespace, "/")
+	if source.Name == "" {
+		source.Name = source.Provider + ":" + source.Kind
+	}
+
+	for i := range source.Remotes {
+		remote := &source.Remotes[i]
+		if remote.URL == "" {
+			return fmt.Errorf("remote %d has no url", i+1)
+		}
+		if remote.Name == "" {
+			name, err := remoteFullName(remote.URL)
+			if err != nil {
+				return fmt.Errorf("remote %s: %w", remote.URL, err)
+			}
+			remote.Name = name
+		}
+		remote.Name = strings.Trim(remote.Name, "/")
+	}
+
+	return nil
+}
+
+// remoteFullName derives <host>/<path> from a git URL: git@host:owner/repo.git and https://host/owner/repo
+// both become host/owner/repo. file:// remotes use "local" as their host.
+func remoteFullName(rawURL string) (string, error) {
+	var host, repoPath string
+	if !strings.Contains(rawURL, "://") {
+		userHost, p, ok := strings.Cut(rawURL, ":")
+		if !ok {
+			return "", fmt.Errorf("not a git URL")
+		}
+		host = userHost[strings.LastIndex(userHost, "@")+1:]
+		repoPath = p
+	} else {
+		parsed, err := url.Parse(rawURL)
+		if err != nil {
+			return "", err
+		}
+		host = parsed.Hostname()
+		if parsed.Scheme == "file" {
+			host = "local"
+		}
+		repoPath = parsed.Path
+	}
+
+	repoPath = strings.TrimSuffix(strings.Trim(repoPath, "/"), ".git")
+	if host == "" || repoPath == "" {
+		return "", fmt.Errorf("cannot derive a name; set one explicitly")
+	}
+
+	return host + "/" + repoPath, nil
+}
+
+func validateGitHubApp(source *model.Source) error {
+	app := source.App
+	if source.Provider != model.ProviderGitHub {
+		return fmt.Errorf("app authentication is only supported for the github provider")
+	}
+	if app.AppID == 0 || app.InstallationID == 0 {
+		return fmt.Errorf("app needs app_id and installation_id")
+	}
+	if app.PrivateKeyEnv == "" && app.PrivateKeyFile == "" {
+		return fmt.Errorf("app needs private_key_file or private_key_env")
+	}
+	if app.PrivateKeyEnv != "" && os.Getenv(app.PrivateKeyEnv) == "" {
+		return fmt.Errorf("private_key_env %s is empty", app.PrivateKeyEnv)
+	}
+
+	return nil
 }
 
-func ImportantURL(config *model.ConfigModel) *model.URL {
-	return &model.URL{
-		GetAllPrivateRepos: "https://api.github.com/user/repos?type=private&per_page=100&page=",
-		GetAllOrgRepos:     "https://api.github.com/orgs/" + config.OrgAccount + "/repos?type=all&per_page=50&page=",
-		GetAllPublicRepos:  "https://api.github.com/users/" + config.ProjectAccount + "/repos?type=public&per_page=50&page=",
+// appCloneHost turns an API base URL into the HTTPS host repositories are cloned from:
+// https://api.github.com becomes https://github.com, https://ghe.example.com/api/v3 becomes https://ghe.example.com
+func appCloneHost(apiURL string) string {
+	if apiURL == DefaultGitHubAPIURL {
+		return "https://github.com"
 	}
+	return strings.TrimSuffix(apiURL, "/api/v3")
 }
@@ -0,0 +1,26 @@
+package config
+
+import "testing"
+
+func TestValidateNamePatterns(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		wantErr  bool
+	}{
+		{name: "none"},
+		{name: "globs", patterns: []string{"acme/*", "[a-z]*"}},
+		{name: "valid regex", patterns: []string{"re:^infra-", "re:(api|web)$"}},
+		{name: "invalid regex", patterns: []string{"acme/*", "re:(unclosed"}, wantErr: true},
+		{name: "invalid regex only", patterns: []string{"re:[z-a]"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateNamePatterns(tt.patterns)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateNamePatterns(%q) error = %v, wantErr %v", tt.patterns, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MishraShardendu22/github-backup/model"
+)
+
+// ExportController pages through an issue-tracker listing and keeps every record verbatim. For listings
+// sorted by updated_at descending, a non-zero notBefore stops paging at the first record older than it.
+// On error the records of the pages read so far are returned with it. Listings with a since= cursor
+// differ on every run, so they bypass the ETag cache.
+func ExportController(ListURL string, token string, notBefore time.Time) ([]model.ExportRecord, error) {
+	client := GitHubAPI()
+	get := client.Get
+	if strings.Contains(ListURL, "since=") {
+		get = client.GetUncached
+	}
+	var page int = 1
+	var allRecords []model.ExportRecord
+
+	for {
+		paginatedUrl := ListURL + strconv.Itoa(page)
+		res, err := get(paginatedUrl, token)
+
+		if err != nil {
+			return allRecords, fmt.Errorf("fetch page %d: %w", page, err)
+		}
+
+		// GitHub answers 410 Gone for the issue listings of repos with issues disabled
+		if res.StatusCode == 410 {
+			break
+		}
+
+		if res.StatusCode != 200 {
+			return allRecords, fmt.Errorf("unexpected status %d on page %d: %s", res.StatusCode, page, string(res.Body))
+		}
+
+		var raws []json.RawMessage
+		if err := json.Unmarshal(res.Body, &raws); err != nil {
+			return allRecords, fmt.Errorf("decode page %d: %w", page, err)
+		}
+
+		if len(raws) == 0 {
+			break
+		}
+
+		for _, raw := range raws {
+			var record model.ExportRecord
+			if err := json.Unmarshal(raw, &record); err != nil {
+				return allRecords, fmt.Errorf("decode record on page %d: %w", page, err)
+			}
+			if !notBefore.IsZero() && record.UpdatedAt.Before(notBefore) {
+				return allRecords, nil
+			}
+			record.Raw = raw
+			allRecords = append(allRecords, record)
+		}
+
+		page++
+	}
+
+	return allRecords, nil
+}
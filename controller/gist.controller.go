@@ -0,0 +1,45 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/MishraShardendu22/github-backup/model"
+)
+
+// GistController pages through a gist listing. On error the gists of the pages read so far are
+// returned with it.
+func GistController(GistURL string, token string) ([]model.Gist, error) {
+	client := GitHubAPI()
+	var page int = 1
+	var allGists []model.Gist
+
+	for {
+		paginatedUrl := GistURL + strconv.Itoa(page)
+		res, err := client.Get(paginatedUrl, token)
+
+		if err != nil {
+			return allGists, fmt.Errorf("fetch page %d: %w", page, err)
+		}
+
+		if res.StatusCode != 200 {
+			return allGists, fmt.Errorf("unexpected status %d on page %d: %s", res.StatusCode, page, string(res.Body))
+		}
+
+		var gists []model.Gist
+		if err := json.Unmarshal(res.Body, &gists); err != nil {
+			return allGists, fmt.Errorf("decode page %d: %w", page, err)
+		}
+
+		if len(gists) == 0 {
+			break
+		}
+
+		allGists = append(allGists, gists...)
+
+		page++
+	}
+
+	return allGists, nil
+}
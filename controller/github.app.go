@@ -0,0 +1,199 @@
+package controller
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/MishraShardendu22/github-backup/model"
+	"github.com/MishraShardendu22/github-backup/util"
+	"go.uber.org/zap"
+)
+
+const (
+	// appJWTLifetime stays under GitHub's 10 minute maximum; iat is backdated for clock drift
+	appJWTLifetime = 9 * time.Minute
+	appJWTBackdate = time.Minute
+	// appTokenRefreshMargin renews installation tokens (valid for one hour) well before they expire,
+	// so a clone started near the end of a token's life does not fail halfway through
+	appTokenRefreshMargin = 10 * time.Minute
+)
+
+// appInstallation caches the current token of one GitHub App installation
+type appInstallation struct {
+	mu        sync.Mutex
+	key       *rsa.PrivateKey
+	token     string
+	expiresAt time.Time
+}
+
+var (
+	appInstallationsMu sync.Mutex
+	appInstallations   = make(map[string]*appInstallation)
+	// appTokenIdentities maps minted installation tokens to a stable name, so rate-limit state and
+	// cached ETags survive the hourly token rotation
+	appTokenIdentities sync.Map
+)
+
+// SourceToken returns the token to use for a source: a fresh installation token for GitHub App
+// sources, otherwise the configured static token
+func SourceToken(source model.Source) (string, error) {
+	if source.App == nil {
+		return source.Token, nil
+	}
+	return InstallationToken(source.APIURL, *source.App)
+}
+
+// InstallationToken returns a cached installation token, exchanging a newly signed app JWT for
+// a new one when the cached token is missing or close to expiry
+func InstallationToken(apiURL string, app model.GitHubApp) (string, error) {
+	identity := appIdentity(apiURL, app)
+
+	appInstallationsMu.Lock()
+	installation, ok := appInstallations[identity]
+	if !ok {
+		installation = &appInstallation{}
+		appInstallations[identity] = installation
+	}
+	appInstallationsMu.Unlock()
+
+	installation.mu.Lock()
+	defer installation.mu.Unlock()
+
+	if installation.token != "" && time.Until(installation.expiresAt) > appTokenRefreshMargin {
+		return installation.token, nil
+	}
+
+	if installation.key == nil {
+		key, err := loadAppPrivateKey(app)
+		if err != nil {
+			return "", err
+		}
+		installation.key = key
+	}
+
+	jwt, err := signAppJWT(app.AppID, installation.key, time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	token, expiresAt, err := exchangeAppJWT(apiURL, app.InstallationID, jwt)
+	if err != nil {
+		return "", err
+	}
+
+	appTokenIdentities.Store(token, identity)
+	if installation.token != "" {
+		appTokenIdentities.Delete(installation.token)
+	}
+	installation.token = token
+	installation.expiresAt = expiresAt
+
+	util.Logger().Info("GitHub App installation token issued",
+		zap.Int64("app_id", app.AppID),
+		zap.Int64("installation_id", app.InstallationID),
+		zap.Time("expires_at", expiresAt),
+	)
+
+	return token, nil
+}
+
+func exchangeAppJWT(apiURL string, installationID int64, jwt string) (string, time.Time, error) {
+	url := fmt.Sprintf("%s/app/installations/%d/access_tokens", apiURL, installationID)
+	res, err := GitHubAPI().http.R().
+		SetHeader("Accept", "application/vnd.github+json").
+		SetAuthToken(jwt).
+		Post(url)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("request installation token: %w", err)
+	}
+	if res.StatusCode() != 201 {
+		return "", time.Time{}, fmt.Errorf("request installation token: unexpected status %d: %s", res.StatusCode(), res.String())
+	}
+
+	var body struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.Unmarshal(res.Body(), &body); err != nil {
+		return "", time.Time{}, fmt.Errorf("decode installation token: %w", err)
+	}
+	if body.Token == "" {
+		return "", time.Time{}, fmt.Errorf("installation token response had no token")
+	}
+
+	return body.Token, body.ExpiresAt, nil
+}
+
+// signAppJWT builds the RS256 JWT GitHub expects from an app: iss is the app ID
+func signAppJWT(appID int64, key *rsa.PrivateKey, now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-appJWTBackdate).Unix(),
+		"exp": now.Add(appJWTLifetime).Unix(),
+		"iss": strconv.FormatInt(appID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	encoding := base64.RawURLEncoding
+	signingInput := encoding.EncodeToString(header) + "." + encoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	signature, err := rsa.SignPKCS1v15(nil, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("sign app JWT: %w", err)
+	}
+
+	return signingInput + "." + encoding.EncodeToString(signature), nil
+}
+
+// loadAppPrivateKey reads the PEM key GitHub issues for an app (PKCS#1), also accepting PKCS#8
+func loadAppPrivateKey(app model.GitHubApp) (*rsa.PrivateKey, error) {
+	var data []byte
+	if app.PrivateKeyEnv != "" {
+		data = []byte(os.Getenv(app.PrivateKeyEnv))
+	} else {
+		var err error
+		data, err = os.ReadFile(app.PrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read app private key: %w", err)
+		}
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("app private key is not PEM encoded")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse app private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("app private key is not an RSA key")
+	}
+
+	return key, nil
+}
+
+func appIdentity(apiURL string, app model.GitHubApp) string {
+	return fmt.Sprintf("app-%d-%d@%s", app.AppID, app.InstallationID, apiURL)
+}
@@ -0,0 +1,279 @@
+package controller
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MishraShardendu22/github-backup/database"
+	"github.com/MishraShardendu22/github-backup/util"
+	"github.com/go-resty/resty/v2"
+	"go.uber.org/zap"
+)
+
+const (
+	maxRateLimitAttempts = 5
+	// secondaryLimitDelay is used when GitHub signals a secondary limit without a Retry-After header
+	secondaryLimitDelay = time.Minute
+	rateLimitResetSlack = 2 * time.Second
+)
+
+// GitHubResponse is a completed GitHub API call, possibly served from the ETag cache
+type GitHubResponse struct {
+	StatusCode int
+	Body       []byte
+	Header     http.Header
+	FromCache  bool
+}
+
+// GitHubClient is shared by every discovery call so rate-limit state is tracked per token
+// across the whole run, and unchanged pages are answered from the SQLite ETag cache
+type GitHubClient struct {
+	http   *resty.Client
+	db     *sql.DB
+	mu     sync.Mutex
+	limits map[string]rateLimitState
+}
+
+type rateLimitState struct {
+	remaining int
+	reset     time.Time
+}
+
+var (
+	sharedClient     *GitHubClient
+	sharedClientOnce sync.Once
+)
+
+// InitGitHubClient enables the persistent ETag cache for the shared client
+func InitGitHubClient(db *sql.DB) {
+	GitHubAPI().db = db
+}
+
+func GitHubAPI() *GitHubClient {
+	sharedClientOnce.Do(func() {
+		sharedClient = &GitHubClient{
+			http:   resty.New(),
+			limits: make(map[string]rateLimitState),
+		}
+	})
+	return sharedClient
+}
+
+// Get performs a GET against the GitHub API. It waits out exhausted primary limits and
+// secondary limits, and sends If-None-Match so unchanged resources cost no quota.
+// Non-2xx responses that are not rate limits are returned to the caller as-is.
+func (c *GitHubClient) Get(url string, token string) (*GitHubResponse, error) {
+	return c.get(url, token, true)
+}
+
+// GetUncached is Get without the ETag cache, for URLs that change on every run and could never be answered from it
+func (c *GitHubClient) GetUncached(url string, token string) (*GitHubResponse, error) {
+	return c.get(url, token, false)
+}
+
+func (c *GitHubClient) get(url string, token string, useCache bool) (*GitHubResponse, error) {
+	key := cacheKey(url, token)
+	var etag string
+	var cachedBody []byte
+	var cached bool
+	if useCache {
+		etag, cachedBody, cached = c.lookupCache(key)
+	}
+
+	var res *resty.Response
+	for attempt := 1; attempt <= maxRateLimitAttempts; attempt++ {
+		c.waitForReset(token, url)
+
+		req := c.http.R().
+			SetHeader("Accept", "application/vnd.github+json").
+			SetHeader("Content-Type", "application/json")
+		if token != "" {
+			req.SetAuthToken(token)
+		}
+		if cached {
+			req.SetHeader("If-None-Match", etag)
+		}
+
+		var err error
+		res, err = req.Get(url)
+		if err != nil {
+			return nil, err
+		}
+
+		c.recordLimits(token, res.Header())
+
+		wait, limited := rateLimitDelay(res)
+		if !limited {
+			break
+		}
+
+		if attempt == maxRateLimitAttempts {
+			break
+		}
+
+		util.Logger().Warn("GitHub rate limit hit; waiting before retry",
+			zap.String("url", url),
+			zap.Int("status", res.StatusCode()),
+			zap.Int("attempt", attempt),
+			zap.Duration("wait", wait),
+		)
+		time.Sleep(wait)
+	}
+
+	if res.StatusCode() == http.StatusNotModified && cached {
+		c.touchCache(key)
+		return &GitHubResponse{
+			StatusCode: http.StatusOK,
+			Body:       cachedBody,
+			Header:     res.Header(),
+			FromCache:  true,
+		}, nil
+	}
+
+	if res.StatusCode() == http.StatusOK && useCache {
+		c.storeCache(key, url, res.Header().Get("ETag"), res.Body())
+	}
+
+	return &GitHubResponse{
+		StatusCode: res.StatusCode(),
+		Body:       res.Body(),
+		Header:     res.Header(),
+	}, nil
+}
+
+// rateLimitDelay reports whether the response is a primary or secondary rate limit and how long to wait
+func rateLimitDelay(res *resty.Response) (time.Duration, bool) {
+	status := res.StatusCode()
+	if status != http.StatusForbidden && status != http.StatusTooManyRequests {
+		return 0, false
+	}
+
+	header := res.Header()
+	if retryAfter := header.Get("Retry-After"); retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil {
+			return time.Duration(seconds) * time.Second, true
+		}
+	}
+
+	if header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, ok := parseReset(header); ok {
+			wait := time.Until(reset) + rateLimitResetSlack
+			if wait < rateLimitResetSlack {
+				wait = rateLimitResetSlack
+			}
+			return wait, true
+		}
+	}
+
+	// Secondary limits can answer 403 while the primary quota is far from spent; only the message tells them apart
+	if status == http.StatusTooManyRequests || strings.Contains(strings.ToLower(res.String()), "secondary rate limit") {
+		return secondaryLimitDelay, true
+	}
+
+	return 0, false
+}
+
+// waitForReset sleeps before a request when the last response for this token said the quota is spent
+func (c *GitHubClient) waitForReset(token, url string) {
+	c.mu.Lock()
+	state, ok := c.limits[tokenKey(token)]
+	c.mu.Unlock()
+
+	if !ok || state.remaining > 0 {
+		return
+	}
+
+	wait := time.Until(state.reset) + rateLimitResetSlack
+	if wait <= 0 {
+		return
+	}
+
+	util.Logger().Warn("GitHub rate limit exhausted; sleeping until reset",
+		zap.String("url", url),
+		zap.Time("reset", state.reset),
+		zap.Duration("wait", wait),
+	)
+	time.Sleep(wait)
+}
+
+func (c *GitHubClient) recordLimits(token string, header http.Header) {
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	reset, ok := parseReset(header)
+	if !ok {
+		return
+	}
+
+	c.mu.Lock()
+	c.limits[tokenKey(token)] = rateLimitState{remaining: remaining, reset: reset}
+	c.mu.Unlock()
+}
+
+func (c *GitHubClient) lookupCache(key string) (string, []byte, bool) {
+	if c.db == nil {
+		return "", nil, false
+	}
+
+	etag, body, found, err := database.GetHTTPCache(c.db, key)
+	if err != nil {
+		util.Logger().Warn("Failed to read GitHub response cache", zap.Error(err))
+		return "", nil, false
+	}
+
+	return etag, body, found
+}
+
+func (c *GitHubClient) storeCache(key, url, etag string, body []byte) {
+	if c.db == nil || etag == "" {
+		return
+	}
+
+	if err := database.SaveHTTPCache(c.db, key, url, etag, body); err != nil {
+		util.Logger().Warn("Failed to store GitHub response cache", zap.Error(err))
+	}
+}
+
+// touchCache keeps entries that keep answering 304 from expiring, since their body never gets rewritten
+func (c *GitHubClient) touchCache(key string) {
+	if c.db == nil {
+		return
+	}
+
+	if err := database.TouchHTTPCache(c.db, key); err != nil {
+		util.Logger().Warn("Failed to refresh GitHub response cache", zap.Error(err))
+	}
+}
+
+func parseReset(header http.Header) (time.Time, bool) {
+	reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(reset, 0), true
+}
+
+// tokenKey identifies a token without keeping the secret itself in memory maps or SQLite
+func tokenKey(token string) string {
+	if token == "" {
+		return "anonymous"
+	}
+	if identity, ok := appTokenIdentities.Load(token); ok {
+		return identity.(string)
+	}
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:8])
+}
+
+// cacheKey scopes cached pages to the token, since different tokens see different repositories
+func cacheKey(url, token string) string {
+	return fmt.Sprintf("%s %s", tokenKey(token), url)
+}
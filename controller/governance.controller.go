@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// APIStatusError is a non-200 answer from the GitHub API. Callers that can do without an endpoint,
+// such as the governance snapshot when the token lacks admin rights, check StatusCode.
+type APIStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIStatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d: %s", e.StatusCode, e.Body)
+}
+
+// GovernanceController pages through a settings listing and keeps every item verbatim. Envelope names
+// the field holding the items for listings wrapped in an object, such as {"total_count": 2, "secrets": [...]};
+// it is empty for plain arrays. A non-200 page comes back as an *APIStatusError, together with the items of
+// the pages read before it.
+func GovernanceController(ListURL string, token string, envelope string) ([]json.RawMessage, error) {
+	client := GitHubAPI()
+	var page int = 1
+	var allItems []json.RawMessage
+
+	for {
+		paginatedUrl := ListURL + strconv.Itoa(page)
+		res, err := client.Get(paginatedUrl, token)
+
+		if err != nil {
+			return allItems, fmt.Errorf("fetch page %d: %w", page, err)
+		}
+
+		if res.StatusCode != 200 {
+			return allItems, &APIStatusError{StatusCode: res.StatusCode, Body: string(res.Body)}
+		}
+
+		var items []json.RawMessage
+		if envelope == "" {
+			err = json.Unmarshal(res.Body, &items)
+		} else {
+			var wrapped map[string]json.RawMessage
+			if err = json.Unmarshal(res.Body, &wrapped); err == nil && wrapped[envelope] != nil {
+				err = json.Unmarshal(wrapped[envelope], &items)
+			}
+		}
+		if err != nil {
+			return allItems, fmt.Errorf("decode page %d: %w", page, err)
+		}
+
+		if len(items) == 0 {
+			break
+		}
+
+		allItems = append(allItems, items...)
+
+		page++
+	}
+
+	return allItems, nil
+}
+
+// GovernanceDocument fetches a single settings object, such as one branch's protection
+func GovernanceDocument(URL string, token string) (json.RawMessage, error) {
+	res, err := GitHubAPI().Get(URL, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != 200 {
+		return nil, &APIStatusError{StatusCode: res.StatusCode, Body: string(res.Body)}
+	}
+
+	return json.RawMessage(res.Body), nil
+}
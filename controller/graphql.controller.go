@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/MishraShardendu22/github-backup/model"
+)
+
+// graphQLURL maps a REST base URL onto the GraphQL endpoint: api.github.com/graphql on github.com,
+// <host>/api/graphql on GitHub Enterprise Server
+func graphQLURL(apiURL string) string {
+	apiURL = strings.TrimRight(apiURL, "/")
+	if strings.HasSuffix(apiURL, "/api/v3") {
+		return strings.TrimSuffix(apiURL, "/v3") + "/graphql"
+	}
+	return apiURL + "/graphql"
+}
+
+// RepoPushStates asks the GraphQL API for the last push time and default-branch commit of up to 100
+// repositories (owner/name) in one query. Repositories GitHub cannot resolve are missing from the result.
+func RepoPushStates(apiURL string, token string, repos []string) (map[string]model.RepoPushState, error) {
+	var params []string
+	var fields []string
+	variables := make(map[string]any, 2*len(repos))
+	for i, repo := range repos {
+		owner, name, ok := strings.Cut(repo, "/")
+		if !ok {
+			continue
+		}
+		params = append(params, fmt.Sprintf("$o%d: String!, $n%d: String!", i, i))
+		fields = append(fields, fmt.Sprintf("r%d: repository(owner: $o%d, name: $n%d) { pushedAt defaultBranchRef { target { oid } } }", i, i, i))
+		variables[fmt.Sprintf("o%d", i)] = owner
+		variables[fmt.Sprintf("n%d", i)] = name
+	}
+	if len(fields) == 0 {
+		return map[string]model.RepoPushState{}, nil
+	}
+	query := fmt.Sprintf("query(%s) { %s }", strings.Join(params, ", "), strings.Join(fields, " "))
+
+	res, err := GitHubAPI().http.R().
+		SetHeader("Content-Type", "application/json").
+		SetAuthToken(token).
+		SetBody(map[string]any{"query": query, "variables": variables}).
+		Post(graphQLURL(apiURL))
+	if err != nil {
+		return nil, fmt.Errorf("graphql push states: %w", err)
+	}
+	if res.StatusCode() != 200 {
+		return nil, fmt.Errorf("graphql push states: unexpected status %d: %s", res.StatusCode(), res.String())
+	}
+
+	var payload struct {
+		Data map[string]*struct {
+			PushedAt         string `json:"pushedAt"`
+			DefaultBranchRef *struct {
+				Target struct {
+					OID string `json:"oid"`
+				} `json:"target"`
+			} `json:"defaultBranchRef"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(res.Body(), &payload); err != nil {
+		return nil, fmt.Errorf("decode push states: %w", err)
+	}
+	// Errors for single repos (deleted, no access) come with the rest of the data; only a missing data object is fatal
+	if payload.Data == nil && len(payload.Errors) > 0 {
+		return nil, fmt.Errorf("graphql push states: %s", payload.Errors[0].Message)
+	}
+
+	states := make(map[string]model.RepoPushState, len(repos))
+	for i, repo := range repos {
+		node := payload.Data[fmt.Sprintf("r%d", i)]
+		if node == nil {
+			continue
+		}
+		state := model.RepoPushState{PushedAt: node.PushedAt}
+		if node.DefaultBranchRef != nil {
+			state.DefaultBranchOID = node.DefaultBranchRef.Target.OID
+		}
+		states[repo] = state
+	}
+
+	return states, nil
+}
@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/MishraShardendu22/github-backup/model"
+)
+
+// StartMigration asks GitHub to prepare an organization migration archive of the given repositories.
+// Repositories are never locked; the archive is a copy, not a move.
+func StartMigration(apiURL string, org string, token string, repos []string) (model.Migration, error) {
+	var migration model.Migration
+
+	res, err := GitHubAPI().http.R().
+		SetHeader("Accept", "application/vnd.github+json").
+		SetAuthToken(token).
+		SetBody(map[string]any{"repositories": repos, "lock_repositories": false}).
+		Post(fmt.Sprintf("%s/orgs/%s/migrations", apiURL, org))
+	if err != nil {
+		return migration, fmt.Errorf("start migration: %w", err)
+	}
+	if res.StatusCode() != 201 {
+		return migration, fmt.Errorf("start migration: unexpected status %d: %s", res.StatusCode(), res.String())
+	}
+
+	if err := json.Unmarshal(res.Body(), &migration); err != nil {
+		return migration, fmt.Errorf("decode migration: %w", err)
+	}
+
+	return migration, nil
+}
+
+// MigrationStatus reads the current state of an organization migration
+func MigrationStatus(apiURL string, org string, token string, id int64) (model.Migration, error) {
+	var migration model.Migration
+
+	res, err := GitHubAPI().Get(fmt.Sprintf("%s/orgs/%s/migrations/%d", apiURL, org, id), token)
+	if err != nil {
+		return migration, err
+	}
+	if res.StatusCode != 200 {
+		return migration, fmt.Errorf("unexpected status %d: %s", res.StatusCode, string(res.Body))
+	}
+
+	if err := json.Unmarshal(res.Body, &migration); err != nil {
+		return migration, fmt.Errorf("decode migration: %w", err)
+	}
+
+	return migration, nil
+}
+
+// DownloadMigrationArchive streams an exported migration archive into dest
+func DownloadMigrationArchive(apiURL string, org string, token string, id int64, dest string) (int64, error) {
+	return downloadFile(fmt.Sprintf("%s/orgs/%s/migrations/%d/archive", apiURL, org, id), token, "application/vnd.github+json", dest)
+}
@@ -0,0 +1,125 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/MishraShardendu22/github-backup/model"
+)
+
+// ReleaseController pages through a repository's releases. On error the releases of the pages read so
+// far are returned with it.
+func ReleaseController(ReleaseURL string, token string) ([]model.Release, error) {
+	client := GitHubAPI()
+	var page int = 1
+	var allReleases []model.Release
+
+	for {
+		paginatedUrl := ReleaseURL + strconv.Itoa(page)
+		res, err := client.Get(paginatedUrl, token)
+
+		if err != nil {
+			return allReleases, fmt.Errorf("fetch page %d: %w", page, err)
+		}
+
+		if res.StatusCode != 200 {
+			return allReleases, fmt.Errorf("unexpected status %d on page %d: %s", res.StatusCode, page, string(res.Body))
+		}
+
+		var releases []model.Release
+		if err := json.Unmarshal(res.Body, &releases); err != nil {
+			return allReleases, fmt.Errorf("decode page %d: %w", page, err)
+		}
+
+		if len(releases) == 0 {
+			break
+		}
+
+		allReleases = append(allReleases, releases...)
+
+		page++
+	}
+
+	return allReleases, nil
+}
+
+// DownloadReleaseAsset streams an asset's binary from its API URL into dest
+func DownloadReleaseAsset(assetURL string, token string, dest string) (int64, error) {
+	return downloadFile(assetURL, token, "application/octet-stream", dest)
+}
+
+const (
+	// downloadTimeout bounds a whole asset or archive download, so one stalled transfer cannot hang the run
+	downloadTimeout = 60 * time.Minute
+	// downloadHeaderTimeout bounds the wait for GitHub or its storage host to start answering
+	downloadHeaderTimeout = time.Minute
+)
+
+var downloadTransport = func() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = downloadHeaderTimeout
+	return transport
+}()
+
+// downloadFile streams a GitHub download into dest without holding it in memory. GitHub redirects to its
+// storage host; net/http drops the Authorization header on that cross-host redirect, as the storage expects.
+// The API's own response is the redirect, so its rate-limit headers are recorded there.
+func downloadFile(fileURL string, token string, accept string, dest string) (int64, error) {
+	GitHubAPI().waitForReset(token, fileURL)
+
+	req, err := http.NewRequest(http.MethodGet, fileURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Accept", accept)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := &http.Client{
+		Transport: downloadTransport,
+		Timeout:   downloadTimeout,
+		CheckRedirect: func(redirect *http.Request, via []*http.Request) error {
+			if redirect.Response != nil {
+				GitHubAPI().recordLimits(token, redirect.Response.Header)
+			}
+			if len(via) >= 10 {
+				return fmt.Errorf("stopped after %d redirects", len(via))
+			}
+			return nil
+		},
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	GitHubAPI().recordLimits(token, res.Header)
+
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return 0, fmt.Errorf("unexpected status %d: %s", res.StatusCode, string(body))
+	}
+
+	file, err := os.Create(dest)
+	if err != nil {
+		return 0, err
+	}
+
+	written, err := io.Copy(file, res.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dest)
+		return 0, err
+	}
+
+	return written, nil
+}
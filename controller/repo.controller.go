@@ -7,136 +7,147 @@ import (
 
 	"github.com/MishraShardendu22/github-backup/model"
 	"github.com/MishraShardendu22/github-backup/util"
-	"github.com/go-resty/resty/v2"
 	"go.uber.org/zap"
 )
 
-// resty use karke I am tryna get all the public repos of a user 
-// and then i will use that list to backup all the repos of that user
-func RepoController(RepoURL string, config model.ConfigModel) []string {
-	client := resty.New()
+// resty use karke I am tryna get all the public repos of a user
+// and then i will use that list to backup all the repos of that user.
+// On error the repos fetched so far are returned alongside it, so callers know the list is incomplete.
+func RepoController(RepoURL string, token string) ([]model.Repo, error) {
+	client := GitHubAPI()
 	var page int = 1
-	var repoNames []string
+	var allRepos []model.Repo
 
 	for {
 		paginatedUrl := RepoURL + strconv.Itoa(page)
-		req := client.R().
-			EnableTrace().
-			SetHeader("Content-Type", "application/json")
-
-		if config.GitHubTokenPersonal != "" {
-			req.SetAuthToken(config.GitHubTokenPersonal)
-		}
-
-		res, err := req.Get(paginatedUrl)
+		res, err := client.Get(paginatedUrl, token)
 
 		if err != nil {
-			util.ErrorHandler(err)
+			return allRepos, fmt.Errorf("fetch page %d: %w", page, err)
 		}
 
-		if res.StatusCode() != 200 {
-			body := string(res.Body())
-			if res.StatusCode() == 401 {
+		if res.StatusCode != 200 {
+			body := string(res.Body)
+			if res.StatusCode == 401 && token != "" {
 				util.Logger().Warn("Unauthorized with provided token; retrying unauthenticated",
-					zap.Int("status", res.StatusCode()),
+					zap.Int("status", res.StatusCode),
 					zap.String("response", body),
 				)
 
-				retryRes, retryErr := client.R().
-					EnableTrace().
-					SetHeader("Content-Type", "application/json").
-					Get(paginatedUrl)
+				retryRes, retryErr := client.Get(paginatedUrl, "")
 
 				if retryErr != nil {
-					util.ErrorHandler(retryErr)
+					return allRepos, fmt.Errorf("fetch page %d unauthenticated: %w", page, retryErr)
 				}
 
-				if retryRes.StatusCode() != 200 {
-					retryBody := string(retryRes.Body())
-					if retryRes.StatusCode() == 403 {
-						util.ErrorHandler(fmt.Errorf("forbidden or rate limited (403). No valid auth; set GITHUB_TOKEN_PERSONAL to increase rate limits. Response: %s", retryBody))
+				if retryRes.StatusCode != 200 {
+					retryBody := string(retryRes.Body)
+					if retryRes.StatusCode == 403 {
+						return allRepos, fmt.Errorf("forbidden or rate limited (403). No valid auth; set a token on the source to increase rate limits. Response: %s", retryBody)
 					}
-					util.ErrorHandler(fmt.Errorf("unexpected status %d after retry: %s", retryRes.StatusCode(), retryBody))
+					return allRepos, fmt.Errorf("unexpected status %d after retry: %s", retryRes.StatusCode, retryBody)
 				}
 
 				res = retryRes
 			}
-			if res.StatusCode() == 403 {
-				util.ErrorHandler(fmt.Errorf("forbidden or rate limited (403). If unauthenticated, set GITHUB_TOKEN_PERSONAL to increase rate limits. Response: %s", body))
+			if res.StatusCode == 403 {
+				return allRepos, fmt.Errorf("forbidden or rate limited (403). If unauthenticated, set a token on the source to increase rate limits. Response: %s", body)
 			}
-			if res.StatusCode() != 200 {
-				util.ErrorHandler(fmt.Errorf("unexpected status %d: %s", res.StatusCode(), body))
+			if res.StatusCode != 200 {
+				return allRepos, fmt.Errorf("unexpected status %d: %s", res.StatusCode, body)
 			}
 		}
 
 		var repos []model.Repo
-		if err := json.Unmarshal(res.Body(), &repos); err != nil {
-			util.ErrorHandler(err)
+		if err := json.Unmarshal(res.Body, &repos); err != nil {
+			return allRepos, fmt.Errorf("decode page %d: %w", page, err)
 		}
 
 		if len(repos) == 0 {
 			break
 		}
 
-		for _, repo := range repos {
-			repoNames = append(repoNames, repo.FullName)
-		}
+		allRepos = append(allRepos, repos...)
 
 		page++
 	}
 
-	return repoNames
+	return allRepos, nil
 }
 
 // same as above but for private repos
-func RepoControllerPrivate(RepoURL string, config model.ConfigModel) []string {
-	client := resty.New()
+func RepoControllerPrivate(RepoURL string, token string) ([]model.Repo, error) {
+	client := GitHubAPI()
 	var page int = 1
-	var repoNames []string
+	var allRepos []model.Repo
 
 	for {
 		paginatedUrl := RepoURL + strconv.Itoa(page)
-		res, err := client.R().
-			EnableTrace().
-			SetHeader("Content-Type", "application/json").
-			SetAuthToken(config.GitHubTokenPrivate).
-			Get(paginatedUrl)
+		res, err := client.Get(paginatedUrl, token)
 
 		if err != nil {
-			util.ErrorHandler(err)
+			return allRepos, fmt.Errorf("fetch page %d: %w", page, err)
 		}
 
-		if res.StatusCode() != 200 {
-			body := string(res.Body())
-			if page == 1 {
-				if res.StatusCode() == 401 {
-					util.ErrorHandler(fmt.Errorf("unauthorized (401). Check GITHUB_TOKEN_PRIVATE in your environment or .env. Response: %s", body))
-				}
-				util.ErrorHandler(fmt.Errorf("unexpected status %d: %s", res.StatusCode(), body))
+		if res.StatusCode != 200 {
+			body := string(res.Body)
+			if res.StatusCode == 401 {
+				return allRepos, fmt.Errorf("unauthorized (401). Check the token configured for this source. Response: %s", body)
 			}
-			util.Logger().Error("Failed to fetch private repos page",
-				zap.Int("page", page),
-				zap.Int("status", res.StatusCode()),
-				zap.String("response", body),
-			)
-			break
+			return allRepos, fmt.Errorf("unexpected status %d on page %d: %s", res.StatusCode, page, body)
 		}
 
 		var repos []model.Repo
-		if err := json.Unmarshal(res.Body(), &repos); err != nil {
-			util.ErrorHandler(err)
+		if err := json.Unmarshal(res.Body, &repos); err != nil {
+			return allRepos, fmt.Errorf("decode page %d: %w", page, err)
 		}
 
 		if len(repos) == 0 {
 			break
 		}
 
-		for _, repo := range repos {
-			repoNames = append(repoNames, repo.FullName)
+		allRepos = append(allRepos, repos...)
+
+		page++
+	}
+
+	return allRepos, nil
+}
+
+// InstallationRepoController lists the repositories a GitHub App installation can access.
+// The endpoint wraps each page in an object, unlike the other listings.
+func InstallationRepoController(RepoURL string, token string) ([]model.Repo, error) {
+	client := GitHubAPI()
+	var page int = 1
+	var allRepos []model.Repo
+
+	for {
+		paginatedUrl := RepoURL + strconv.Itoa(page)
+		res, err := client.Get(paginatedUrl, token)
+
+		if err != nil {
+			return allRepos, fmt.Errorf("fetch page %d: %w", page, err)
 		}
 
+		if res.StatusCode != 200 {
+			return allRepos, fmt.Errorf("unexpected status %d on page %d: %s", res.StatusCode, page, string(res.Body))
+		}
+
+		var body struct {
+			Repositories []model.Repo `json:"repositories"`
+		}
+		if err := json.Unmarshal(res.Body, &body); err != nil {
+			return allRepos, fmt.Errorf("decode page %d: %w", page, err)
+		}
+
+		if len(body.Repositories) == 0 {
+			break
+		}
+
+		allRepos = append(allRepos, body.Repositories...)
+
 		page++
 	}
 
-	return repoNames
+	return allRepos, nil
 }
@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TokenInfo is what the API reveals about a token: who it belongs to, its classic OAuth scopes,
+// when it expires and how much of the core rate limit is left
+type TokenInfo struct {
+	Login         string
+	Scopes        []string
+	ScopesKnown   bool
+	ExpiresAt     time.Time
+	RateLimit     int
+	RateRemaining int
+	RateReset     time.Time
+}
+
+// tokenExpirationLayout is the format of GitHub's GitHub-Authentication-Token-Expiration header
+const tokenExpirationLayout = "2006-01-02 15:04:05 MST"
+
+// InspectGitHubToken validates a token against GET /user and reads its rate limit. Installation
+// tokens cannot call /user, so for them only the rate limit is checked. The ETag cache is bypassed:
+// a cached answer says nothing about whether the token still works.
+func InspectGitHubToken(apiURL string, token string, installation bool) (TokenInfo, error) {
+	var info TokenInfo
+
+	if !installation {
+		res, err := GitHubAPI().http.R().
+			SetHeader("Accept", "application/vnd.github+json").
+			SetAuthToken(token).
+			Get(apiURL + "/user")
+		if err != nil {
+			return info, err
+		}
+		if res.StatusCode() != 200 {
+			return info, fmt.Errorf("GET /user returned %d: %s", res.StatusCode(), strings.TrimSpace(res.String()))
+		}
+
+		var user struct {
+			Login string `json:"login"`
+		}
+		if err := json.Unmarshal(res.Body(), &user); err != nil {
+			return info, fmt.Errorf("decode /user: %w", err)
+		}
+		info.Login = user.Login
+
+		// Fine-grained tokens and app tokens send no X-OAuth-Scopes header at all
+		if header, ok := res.Header()["X-Oauth-Scopes"]; ok {
+			info.ScopesKnown = true
+			for _, scope := range strings.Split(strings.Join(header, ","), ",") {
+				if scope = strings.TrimSpace(scope); scope != "" {
+					info.Scopes = append(info.Scopes, scope)
+				}
+			}
+		}
+
+		if expiration := res.Header().Get("GitHub-Authentication-Token-Expiration"); expiration != "" {
+			if expiresAt, err := time.Parse(tokenExpirationLayout, expiration); err == nil {
+				info.ExpiresAt = expiresAt
+			}
+		}
+	}
+
+	res, err := GitHubAPI().http.R().
+		SetHeader("Accept", "application/vnd.github+json").
+		SetAuthToken(token).
+		Get(apiURL + "/rate_limit")
+	if err != nil {
+		return info, err
+	}
+	if res.StatusCode() != 200 {
+		return info, fmt.Errorf("GET /rate_limit returned %d: %s", res.StatusCode(), strings.TrimSpace(res.String()))
+	}
+
+	info.RateLimit, _ = strconv.Atoi(res.Header().Get("X-RateLimit-Limit"))
+	info.RateRemaining, _ = strconv.Atoi(res.Header().Get("X-RateLimit-Remaining"))
+	if reset, ok := parseReset(res.Header()); ok {
+		info.RateReset = reset
+	}
+
+	return info, nil
+}
+
+// CheckForgeToken validates a GitLab or Gitea token by fetching the user it belongs to
+func CheckForgeToken(apiURL string, token string) (string, error) {
+	res, err := GitHubAPI().http.R().
+		SetAuthToken(token).
+		Get(apiURL + "/user")
+	if err != nil {
+		return "", err
+	}
+	if res.StatusCode() != 200 {
+		return "", fmt.Errorf("GET /user returned %d: %s", res.StatusCode(), strings.TrimSpace(res.String()))
+	}
+
+	var user struct {
+		Login    string `json:"login"`
+		Username string `json:"username"`
+	}
+	if err := json.Unmarshal(res.Body(), &user); err != nil {
+		return "", fmt.Errorf("decode /user: %w", err)
+	}
+	if user.Login != "" {
+		return user.Login, nil
+	}
+	return user.Username, nil
+}
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+const createExportCursorsTableSQL = `
+	CREATE TABLE IF NOT EXISTS export_cursors (
+		full_name TEXT PRIMARY KEY,
+		cursor TEXT NOT NULL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+`
+
+const selectExportCursorSQL = `
+	SELECT cursor FROM export_cursors WHERE full_name = ?
+`
+
+const upsertExportCursorSQL = `
+	INSERT INTO export_cursors (full_name, cursor, updated_at)
+	VALUES (?, ?, CURRENT_TIMESTAMP)
+	ON CONFLICT(full_name) DO UPDATE SET
+		cursor = excluded.cursor,
+		updated_at = CURRENT_TIMESTAMP;
+`
+
+const renameExportCursorSQL = `
+	UPDATE export_cursors SET full_name = ? WHERE full_name = ?
+`
+
+const deleteExportCursorSQL = `
+	DELETE FROM export_cursors WHERE full_name = ?
+`
+
+// GetExportCursor returns the time up to which a repo's issues, pull requests and comments were exported.
+// The zero time and false mean nothing was exported yet.
+func GetExportCursor(db *sql.DB, fullName string) (time.Time, bool, error) {
+	var cursor string
+	err := db.QueryRow(selectExportCursorSQL, fullName).Scan(&cursor)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return time.Time{}, false, nil
+		}
+		return time.Time{}, false, err
+	}
+
+	parsed, err := time.Parse(time.RFC3339, cursor)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	return parsed, true, nil
+}
+
+func SaveExportCursor(db *sql.DB, fullName string, cursor time.Time) error {
+	_, err := db.Exec(upsertExportCursorSQL, fullName, cursor.UTC().Format(time.RFC3339))
+	return err
+}
+
+// RenameExportCursor keeps the export cursor with a renamed or transferred repo
+func RenameExportCursor(db *sql.DB, oldFullName, newFullName string) error {
+	_, err := db.Exec(renameExportCursorSQL, newFullName, oldFullName)
+	return err
+}
+
+func DeleteExportCursor(db *sql.DB, fullName string) error {
+	_, err := db.Exec(deleteExportCursorSQL, fullName)
+	return err
+}
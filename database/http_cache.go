@@ -0,0 +1,66 @@
+package database
+
+import "database/sql"
+
+const createHTTPCacheTableSQL = `
+	CREATE TABLE IF NOT EXISTS http_cache (
+		cache_key TEXT PRIMARY KEY,
+		url TEXT NOT NULL,
+		etag TEXT NOT NULL,
+		body BLOB NOT NULL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+`
+
+const selectHTTPCacheSQL = `
+	SELECT etag, body FROM http_cache WHERE cache_key = ?
+`
+
+const upsertHTTPCacheSQL = `
+	INSERT INTO http_cache (cache_key, url, etag, body, updated_at)
+	VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+	ON CONFLICT(cache_key) DO UPDATE SET
+		url = excluded.url,
+		etag = excluded.etag,
+		body = excluded.body,
+		updated_at = CURRENT_TIMESTAMP;
+`
+
+const touchHTTPCacheSQL = `
+	UPDATE http_cache SET updated_at = CURRENT_TIMESTAMP WHERE cache_key = ?
+`
+
+const cleanupHTTPCacheSQL = `
+	DELETE FROM http_cache
+	WHERE updated_at <= datetime('now', '-30 days')
+`
+
+// GetHTTPCache returns the stored ETag and body for a conditional GitHub API request
+func GetHTTPCache(db *sql.DB, key string) (string, []byte, bool, error) {
+	var etag string
+	var body []byte
+	err := db.QueryRow(selectHTTPCacheSQL, key).Scan(&etag, &body)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil, false, nil
+		}
+		return "", nil, false, err
+	}
+
+	return etag, body, true, nil
+}
+
+func SaveHTTPCache(db *sql.DB, key, url, etag string, body []byte) error {
+	if etag == "" {
+		return nil
+	}
+
+	_, err := db.Exec(upsertHTTPCacheSQL, key, url, etag, body)
+	return err
+}
+
+// TouchHTTPCache marks a cached response as used so the 30-day cleanup only drops entries nobody asks for
+func TouchHTTPCache(db *sql.DB, key string) error {
+	_, err := db.Exec(touchHTTPCacheSQL, key)
+	return err
+}
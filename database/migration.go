@@ -0,0 +1,32 @@
+package database
+
+import "database/sql"
+
+const createAppliedMigrationsTableSQL = `
+	CREATE TABLE IF NOT EXISTS applied_migrations (
+		name TEXT PRIMARY KEY,
+		applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+`
+
+const selectAppliedMigrationSQL = `
+	SELECT COUNT(1) FROM applied_migrations WHERE name = ?
+`
+
+const insertAppliedMigrationSQL = `
+	INSERT INTO applied_migrations (name) VALUES (?) ON CONFLICT(name) DO NOTHING;
+`
+
+// IsMigrationApplied reports whether a one-time data migration already ran against this database
+func IsMigrationApplied(db *sql.DB, name string) (bool, error) {
+	var count int
+	if err := db.QueryRow(selectAppliedMigrationSQL, name).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func MarkMigrationApplied(db *sql.DB, name string) error {
+	_, err := db.Exec(insertAppliedMigrationSQL, name)
+	return err
+}
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/MishraShardendu22/github-backup/model"
+)
+
+const createMirrorStatesTableSQL = `
+	CREATE TABLE IF NOT EXISTS mirror_states (
+		full_name TEXT PRIMARY KEY,
+		refs TEXT NOT NULL,
+		bundles TEXT NOT NULL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+`
+
+const selectMirrorStateSQL = `
+	SELECT refs, bundles FROM mirror_states WHERE full_name = ?
+`
+
+const upsertMirrorStateSQL = `
+	INSERT INTO mirror_states (full_name, refs, bundles, updated_at)
+	VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+	ON CONFLICT(full_name) DO UPDATE SET
+		refs = excluded.refs,
+		bundles = excluded.bundles,
+		updated_at = CURRENT_TIMESTAMP;
+`
+
+const renameMirrorStateSQL = `
+	UPDATE mirror_states SET full_name = ? WHERE full_name = ?
+`
+
+const deleteMirrorStateSQL = `
+	DELETE FROM mirror_states WHERE full_name = ?
+`
+
+// GetMirrorState returns the refs and bundle chain of a repo's last pushed mirror backup.
+// false means the repo has no bundle to build on yet.
+func GetMirrorState(db *sql.DB, fullName string) (model.MirrorState, bool, error) {
+	state := model.MirrorState{FullName: fullName}
+
+	var refs, bundles string
+	err := db.QueryRow(selectMirrorStateSQL, fullName).Scan(&refs, &bundles)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return state, false, nil
+		}
+		return state, false, err
+	}
+
+	if err := json.Unmarshal([]byte(refs), &state.Refs); err != nil {
+		return state, false, err
+	}
+	if err := json.Unmarshal([]byte(bundles), &state.Bundles); err != nil {
+		return state, false, err
+	}
+
+	return state, true, nil
+}
+
+func SaveMirrorState(db *sql.DB, state model.MirrorState) error {
+	refs, err := json.Marshal(state.Refs)
+	if err != nil {
+		return err
+	}
+	bundles, err := json.Marshal(state.Bundles)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(upsertMirrorStateSQL, state.FullName, string(refs), string(bundles))
+	return err
+}
+
+// RenameMirrorState keeps the bundle chain with a renamed or transferred repo, whose .mirror directory moves with it
+func RenameMirrorState(db *sql.DB, oldFullName, newFullName string) error {
+	_, err := db.Exec(renameMirrorStateSQL, newFullName, oldFullName)
+	return err
+}
+
+func DeleteMirrorState(db *sql.DB, fullName string) error {
+	_, err := db.Exec(deleteMirrorStateSQL, fullName)
+	return err
+}
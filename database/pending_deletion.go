@@ -0,0 +1,69 @@
+package database
+
+import "database/sql"
+
+const createPendingDeletionsTableSQL = `
+	CREATE TABLE IF NOT EXISTS pending_deletions (
+		full_name TEXT PRIMARY KEY,
+		reason TEXT NOT NULL,
+		detected_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+`
+
+const insertPendingDeletionSQL = `
+	INSERT INTO pending_deletions (full_name, reason) VALUES (?, ?);
+`
+
+const selectPendingDeletionsSQL = `
+	SELECT full_name FROM pending_deletions ORDER BY full_name
+`
+
+const clearPendingDeletionsSQL = `
+	DELETE FROM pending_deletions
+`
+
+// ReplacePendingDeletions stores the deletion set refused by the safeguard, replacing any previous one
+func ReplacePendingDeletions(db *sql.DB, fullNames []string, reason string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(clearPendingDeletionsSQL); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	for _, fullName := range fullNames {
+		if _, err := tx.Exec(insertPendingDeletionSQL, fullName, reason); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func GetPendingDeletions(db *sql.DB) ([]string, error) {
+	rows, err := db.Query(selectPendingDeletionsSQL)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+
+	return names, rows.Err()
+}
+
+func ClearPendingDeletions(db *sql.DB) error {
+	_, err := db.Exec(clearPendingDeletionsSQL)
+	return err
+}
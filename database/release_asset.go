@@ -0,0 +1,104 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/MishraShardendu22/github-backup/model"
+)
+
+const createReleaseAssetsTableSQL = `
+	CREATE TABLE IF NOT EXISTS release_assets (
+		asset_id INTEGER PRIMARY KEY,
+		release_id INTEGER NOT NULL,
+		full_name TEXT NOT NULL,
+		name TEXT NOT NULL,
+		size INTEGER NOT NULL DEFAULT 0,
+		asset_updated_at TEXT NOT NULL DEFAULT '',
+		backup TEXT NOT NULL,
+		backed_up_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_release_assets_repo ON release_assets(full_name);
+`
+
+const selectReleaseAssetsSQL = `
+	SELECT asset_id, release_id, full_name, name, size, asset_updated_at, backup
+	FROM release_assets WHERE full_name = ?
+`
+
+const upsertReleaseAssetSQL = `
+	INSERT INTO release_assets (asset_id, release_id, full_name, name, size, asset_updated_at, backup, backed_up_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+	ON CONFLICT(asset_id) DO UPDATE SET
+		release_id = excluded.release_id,
+		full_name = excluded.full_name,
+		name = excluded.name,
+		size = excluded.size,
+		asset_updated_at = excluded.asset_updated_at,
+		backup = excluded.backup,
+		backed_up_at = CURRENT_TIMESTAMP;
+`
+
+const deleteReleaseAssetSQL = `
+	DELETE FROM release_assets WHERE asset_id = ?
+`
+
+const renameReleaseAssetsSQL = `
+	UPDATE release_assets SET full_name = ? WHERE full_name = ?
+`
+
+const deleteRepoReleaseAssetsSQL = `
+	DELETE FROM release_assets WHERE full_name = ?
+`
+
+// GetReleaseAssets returns the assets already backed up for a repo, keyed by GitHub asset ID
+func GetReleaseAssets(db *sql.DB, fullName string) (map[int]model.ReleaseAssetRecord, error) {
+	rows, err := db.Query(selectReleaseAssetsSQL, fullName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	assets := make(map[int]model.ReleaseAssetRecord)
+	for rows.Next() {
+		var record model.ReleaseAssetRecord
+		var backup string
+		if err := rows.Scan(&record.AssetID, &record.ReleaseID, &record.FullName, &record.Name,
+			&record.Size, &record.UpdatedAt, &backup); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(backup), &record.Backup); err != nil {
+			return nil, err
+		}
+		assets[record.AssetID] = record
+	}
+
+	return assets, rows.Err()
+}
+
+func SaveReleaseAsset(db *sql.DB, record model.ReleaseAssetRecord) error {
+	backup, err := json.Marshal(record.Backup)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(upsertReleaseAssetSQL, record.AssetID, record.ReleaseID, record.FullName, record.Name,
+		record.Size, record.UpdatedAt, string(backup))
+	return err
+}
+
+func DeleteReleaseAsset(db *sql.DB, assetID int) error {
+	_, err := db.Exec(deleteReleaseAssetSQL, assetID)
+	return err
+}
+
+// RenameReleaseAssets keeps the asset bookkeeping with a renamed or transferred repo
+func RenameReleaseAssets(db *sql.DB, oldFullName, newFullName string) error {
+	_, err := db.Exec(renameReleaseAssetsSQL, newFullName, oldFullName)
+	return err
+}
+
+func DeleteRepoReleaseAssets(db *sql.DB, fullName string) error {
+	_, err := db.Exec(deleteRepoReleaseAssetsSQL, fullName)
+	return err
+}
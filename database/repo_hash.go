@@ -2,6 +2,7 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 
 	"github.com/MishraShardendu22/github-backup/model"
 )
@@ -13,33 +14,96 @@ const reposTableSQL = `
 		full_name TEXT NOT NULL UNIQUE,
 		clone_url TEXT NOT NULL,
 		latest_commit_hash TEXT NOT NULL,
+		github_id INTEGER NOT NULL DEFAULT 0,
+		description TEXT NOT NULL DEFAULT '',
+		language TEXT NOT NULL DEFAULT '',
+		topics TEXT NOT NULL DEFAULT '[]',
+		visibility TEXT NOT NULL DEFAULT '',
+		default_branch TEXT NOT NULL DEFAULT '',
+		is_fork INTEGER NOT NULL DEFAULT 0,
+		is_archived INTEGER NOT NULL DEFAULT 0,
+		size_kb INTEGER NOT NULL DEFAULT 0,
+		pushed_at TEXT NOT NULL DEFAULT '',
 		last_backed_up_at DATETIME,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);
 `
 
+// repoColumnMigrations adds the metadata columns to repos tables created before they existed
+var repoColumnMigrations = map[string]string{
+	"github_id":      "INTEGER NOT NULL DEFAULT 0",
+	"description":    "TEXT NOT NULL DEFAULT ''",
+	"language":       "TEXT NOT NULL DEFAULT ''",
+	"topics":         "TEXT NOT NULL DEFAULT '[]'",
+	"visibility":     "TEXT NOT NULL DEFAULT ''",
+	"default_branch": "TEXT NOT NULL DEFAULT ''",
+	"is_fork":        "INTEGER NOT NULL DEFAULT 0",
+	"is_archived":    "INTEGER NOT NULL DEFAULT 0",
+	"size_kb":        "INTEGER NOT NULL DEFAULT 0",
+	"pushed_at":      "TEXT NOT NULL DEFAULT ''",
+}
+
 const upsertRepoSQL = `
-	INSERT INTO repos (name, full_name, clone_url, latest_commit_hash, last_backed_up_at, updated_at)
-	VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+	INSERT INTO repos (
+		name, full_name, clone_url, latest_commit_hash,
+		github_id, description, language, topics, visibility, default_branch,
+		is_fork, is_archived, size_kb, pushed_at,
+		last_backed_up_at, updated_at
+	)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
 	ON CONFLICT(full_name) DO UPDATE SET
 		name = excluded.name,
 		clone_url = excluded.clone_url,
 		latest_commit_hash = excluded.latest_commit_hash,
+		github_id = excluded.github_id,
+		description = excluded.description,
+		language = excluded.language,
+		topics = excluded.topics,
+		visibility = excluded.visibility,
+		default_branch = excluded.default_branch,
+		is_fork = excluded.is_fork,
+		is_archived = excluded.is_archived,
+		size_kb = excluded.size_kb,
+		pushed_at = excluded.pushed_at,
 		last_backed_up_at = CURRENT_TIMESTAMP,
 		updated_at = CURRENT_TIMESTAMP;
 `
 
+const updateRepoMetadataSQL = `
+	UPDATE repos SET
+		github_id = ?, description = ?, language = ?, topics = ?, visibility = ?, default_branch = ?,
+		is_fork = ?, is_archived = ?, size_kb = ?, pushed_at = ?
+	WHERE full_name = ?
+`
+
+const repoColumnsSQL = `
+	id, name, full_name, clone_url, latest_commit_hash,
+	github_id, description, language, topics, visibility, default_branch,
+	is_fork, is_archived, size_kb, pushed_at,
+	last_backed_up_at, created_at, updated_at
+`
+
 const selectRepoSQL = `
-	SELECT id, name, full_name, clone_url, latest_commit_hash, last_backed_up_at, created_at, updated_at
+	SELECT ` + repoColumnsSQL + `
 	FROM repos WHERE full_name = ?
 `
 
 const selectAllReposSQL = `
-	SELECT id, name, full_name, clone_url, latest_commit_hash, last_backed_up_at, created_at, updated_at
+	SELECT ` + repoColumnsSQL + `
 	FROM repos ORDER BY id
 `
 
+const renameRepoSQL = `
+	UPDATE repos SET full_name = ?, name = ?, updated_at = CURRENT_TIMESTAMP
+	WHERE full_name = ?
+`
+
+const resetRepoHashSQL = `
+	UPDATE repos SET latest_commit_hash = '', updated_at = CURRENT_TIMESTAMP
+	WHERE full_name = ?
+`
+
 const deleteRepoSQL = `
 	DELETE FROM repos WHERE full_name = ?
 `
@@ -53,13 +117,45 @@ const repoStatsSQL = `
 	FROM repos
 `
 
-func GetRepo(db *sql.DB, fullName string) (model.RepoRecord, bool, error) {
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRepo(row rowScanner) (model.RepoRecord, error) {
 	var r model.RepoRecord
-	err := db.QueryRow(selectRepoSQL, fullName).Scan(
-		&r.ID, &r.Name, &r.FullName, &r.CloneURL,
-		&r.LatestCommitHash, &r.LastBackedUpAt,
-		&r.CreatedAt, &r.UpdatedAt,
+	var topics string
+	err := row.Scan(
+		&r.ID, &r.Name, &r.FullName, &r.CloneURL, &r.LatestCommitHash,
+		&r.GitHubID, &r.Description, &r.Language, &topics, &r.Visibility, &r.DefaultBranch,
+		&r.Fork, &r.Archived, &r.SizeKB, &r.PushedAt,
+		&r.LastBackedUpAt, &r.CreatedAt, &r.UpdatedAt,
 	)
+	if err != nil {
+		return r, err
+	}
+
+	if topics != "" {
+		_ = json.Unmarshal([]byte(topics), &r.Topics)
+	}
+
+	return r, nil
+}
+
+func encodeTopics(topics []string) string {
+	if len(topics) == 0 {
+		return "[]"
+	}
+
+	encoded, err := json.Marshal(topics)
+	if err != nil {
+		return "[]"
+	}
+
+	return string(encoded)
+}
+
+func GetRepo(db *sql.DB, fullName string) (model.RepoRecord, bool, error) {
+	r, err := scanRepo(db.QueryRow(selectRepoSQL, fullName))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return r, false, nil
@@ -70,12 +166,26 @@ func GetRepo(db *sql.DB, fullName string) (model.RepoRecord, bool, error) {
 	return r, true, nil
 }
 
-func UpsertRepo(db *sql.DB, name, fullName, cloneURL, hash string) error {
-	if fullName == "" || hash == "" {
+func UpsertRepo(db *sql.DB, repo model.Repo, name, cloneURL, hash string) error {
+	if repo.FullName == "" || hash == "" {
 		return nil
 	}
 
-	_, err := db.Exec(upsertRepoSQL, name, fullName, cloneURL, hash)
+	_, err := db.Exec(upsertRepoSQL,
+		name, repo.FullName, cloneURL, hash,
+		repo.GitHubID(), repo.Description, repo.Language, encodeTopics(repo.Topics), repo.Visibility, repo.DefaultBranch,
+		repo.Fork, repo.Archived, repo.Size, repo.PushedAt,
+	)
+	return err
+}
+
+// UpdateRepoMetadata refreshes the metadata columns of an already tracked repo without touching its hash
+func UpdateRepoMetadata(db *sql.DB, repo model.Repo) error {
+	_, err := db.Exec(updateRepoMetadataSQL,
+		repo.GitHubID(), repo.Description, repo.Language, encodeTopics(repo.Topics), repo.Visibility, repo.DefaultBranch,
+		repo.Fork, repo.Archived, repo.Size, repo.PushedAt,
+		repo.FullName,
+	)
 	return err
 }
 
@@ -88,12 +198,8 @@ func GetAllReposFromDB(db *sql.DB) ([]model.RepoRecord, error) {
 
 	var repos []model.RepoRecord
 	for rows.Next() {
-		var r model.RepoRecord
-		if err := rows.Scan(
-			&r.ID, &r.Name, &r.FullName, &r.CloneURL,
-			&r.LatestCommitHash, &r.LastBackedUpAt,
-			&r.CreatedAt, &r.UpdatedAt,
-		); err != nil {
+		r, err := scanRepo(rows)
+		if err != nil {
 			return nil, err
 		}
 		repos = append(repos, r)
@@ -106,6 +212,18 @@ func GetAllReposFromDB(db *sql.DB) ([]model.RepoRecord, error) {
 	return repos, nil
 }
 
+// RenameRepo moves a tracked repo to its new full name, keeping its hash and backup history
+func RenameRepo(db *sql.DB, oldFullName, newFullName, newName string) error {
+	_, err := db.Exec(renameRepoSQL, newFullName, newName, oldFullName)
+	return err
+}
+
+// ResetRepoHash forgets the recorded hash so the next run backs the repo up again
+func ResetRepoHash(db *sql.DB, fullName string) error {
+	_, err := db.Exec(resetRepoHashSQL, fullName)
+	return err
+}
+
 func DeleteRepo(db *sql.DB, fullName string) error {
 	_, err := db.Exec(deleteRepoSQL, fullName)
 	return err
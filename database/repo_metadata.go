@@ -0,0 +1,62 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/MishraShardendu22/github-backup/model"
+)
+
+const createRepoMetadataHistoryTableSQL = `
+	CREATE TABLE IF NOT EXISTS repo_metadata_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		full_name TEXT NOT NULL,
+		github_id INTEGER NOT NULL DEFAULT 0,
+		metadata TEXT NOT NULL,
+		captured_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_repo_metadata_history_repo ON repo_metadata_history(full_name, id);
+`
+
+const selectLatestRepoMetadataSQL = `
+	SELECT metadata FROM repo_metadata_history
+	WHERE full_name = ?
+	ORDER BY id DESC LIMIT 1
+`
+
+const insertRepoMetadataSQL = `
+	INSERT INTO repo_metadata_history (full_name, github_id, metadata) VALUES (?, ?, ?);
+`
+
+// RecordRepoMetadata appends a history row when the repo's descriptive metadata differs from the last
+// captured version and keeps the metadata columns of the repos table, counters included, in sync
+func RecordRepoMetadata(db *sql.DB, repo model.Repo) (bool, error) {
+	if repo.FullName == "" {
+		return false, nil
+	}
+
+	encoded, err := json.Marshal(repo.Descriptor())
+	if err != nil {
+		return false, err
+	}
+
+	var latest string
+	err = db.QueryRow(selectLatestRepoMetadataSQL, repo.FullName).Scan(&latest)
+	if err != nil && err != sql.ErrNoRows {
+		return false, err
+	}
+
+	if err := UpdateRepoMetadata(db, repo); err != nil {
+		return false, err
+	}
+
+	if latest == string(encoded) {
+		return false, nil
+	}
+
+	if _, err := db.Exec(insertRepoMetadataSQL, repo.FullName, repo.GitHubID(), string(encoded)); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
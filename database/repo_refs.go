@@ -0,0 +1,91 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/MishraShardendu22/github-backup/model"
+)
+
+const createRepoRefsTableSQL = `
+	CREATE TABLE IF NOT EXISTS repo_refs (
+		full_name TEXT PRIMARY KEY,
+		fingerprint TEXT NOT NULL,
+		refs TEXT NOT NULL,
+		pushed_at TEXT NOT NULL DEFAULT '',
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+`
+
+// repoRefsColumnMigrations adds the columns of repo_refs tables created before they existed
+var repoRefsColumnMigrations = map[string]string{
+	"pushed_at": "TEXT NOT NULL DEFAULT ''",
+}
+
+const selectRepoRefsSQL = `
+	SELECT fingerprint, refs, pushed_at FROM repo_refs WHERE full_name = ?
+`
+
+const upsertRepoRefsSQL = `
+	INSERT INTO repo_refs (full_name, fingerprint, refs, pushed_at, updated_at)
+	VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+	ON CONFLICT(full_name) DO UPDATE SET
+		fingerprint = excluded.fingerprint,
+		refs = excluded.refs,
+		pushed_at = excluded.pushed_at,
+		updated_at = CURRENT_TIMESTAMP;
+`
+
+const renameRepoRefsSQL = `
+	UPDATE repo_refs SET full_name = ? WHERE full_name = ?
+`
+
+const deleteRepoRefsSQL = `
+	DELETE FROM repo_refs WHERE full_name = ?
+`
+
+// GetRepoRefs returns the refs recorded at a repo's last pushed backup.
+// false means the repo was last backed up before refs were tracked, or never.
+func GetRepoRefs(db *sql.DB, fullName string) (model.RepoRefs, bool, error) {
+	refs := model.RepoRefs{FullName: fullName}
+
+	var encoded string
+	err := db.QueryRow(selectRepoRefsSQL, fullName).Scan(&refs.Fingerprint, &encoded, &refs.PushedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return refs, false, nil
+		}
+		return refs, false, err
+	}
+
+	if err := json.Unmarshal([]byte(encoded), &refs.Refs); err != nil {
+		return refs, false, err
+	}
+
+	return refs, true, nil
+}
+
+func SaveRepoRefs(db *sql.DB, refs model.RepoRefs) error {
+	if refs.FullName == "" || refs.Fingerprint == "" {
+		return nil
+	}
+
+	encoded, err := json.Marshal(refs.Refs)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(upsertRepoRefsSQL, refs.FullName, refs.Fingerprint, string(encoded), refs.PushedAt)
+	return err
+}
+
+// RenameRepoRefs keeps the recorded refs with a renamed or transferred repo so it is not backed up again for the rename alone
+func RenameRepoRefs(db *sql.DB, oldFullName, newFullName string) error {
+	_, err := db.Exec(renameRepoRefsSQL, newFullName, oldFullName)
+	return err
+}
+
+func DeleteRepoRefs(db *sql.DB, fullName string) error {
+	_, err := db.Exec(deleteRepoRefsSQL, fullName)
+	return err
+}
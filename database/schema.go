@@ -1,20 +1,38 @@
 package database
 
-import "database/sql"
+import (
+	"database/sql"
+	"fmt"
+)
 
 func InitSchema(db *sql.DB) error {
-	statements := []string{createLogsTableSQL, reposTableSQL}
+	statements := []string{
+		createLogsTableSQL,
+		reposTableSQL,
+		createRepoMetadataHistoryTableSQL,
+		createHTTPCacheTableSQL,
+		createPendingDeletionsTableSQL,
+		createTombstonesTableSQL,
+		createAppliedMigrationsTableSQL,
+		createExportCursorsTableSQL,
+		createReleaseAssetsTableSQL,
+		createMirrorStatesTableSQL,
+		createRepoRefsTableSQL,
+	}
 	for _, statement := range statements {
 		if _, err := db.Exec(statement); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	if err := addMissingColumns(db, "repos", repoColumnMigrations); err != nil {
+		return err
+	}
+	return addMissingColumns(db, "repo_refs", repoRefsColumnMigrations)
 }
 
 func CleanupExpired(db *sql.DB) error {
-	statements := []string{cleanupFailedLogsSQL}
+	statements := []string{cleanupFailedLogsSQL, cleanupHTTPCacheSQL}
 	for _, statement := range statements {
 		if _, err := db.Exec(statement); err != nil {
 			return err
@@ -33,3 +51,45 @@ func MigrateSchema(db *sql.DB) error {
 	}
 	return nil
 }
+
+// addMissingColumns brings tables created by older versions up to date, since CREATE TABLE IF NOT EXISTS
+// leaves existing tables untouched
+func addMissingColumns(db *sql.DB, table string, columns map[string]string) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[name] = true
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for column, definition := range columns {
+		if existing[column] {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
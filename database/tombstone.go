@@ -0,0 +1,122 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/MishraShardendu22/github-backup/model"
+)
+
+const createTombstonesTableSQL = `
+	CREATE TABLE IF NOT EXISTS tombstones (
+		full_name TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		github_id INTEGER NOT NULL DEFAULT 0,
+		clone_url TEXT NOT NULL,
+		latest_commit_hash TEXT NOT NULL,
+		archive_path TEXT NOT NULL,
+		first_missing_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+`
+
+const insertTombstoneSQL = `
+	INSERT INTO tombstones (full_name, name, github_id, clone_url, latest_commit_hash, archive_path)
+	VALUES (?, ?, ?, ?, ?, ?)
+	ON CONFLICT(full_name) DO NOTHING;
+`
+
+const tombstoneColumnsSQL = `
+	full_name, name, github_id, clone_url, latest_commit_hash, archive_path, first_missing_at
+`
+
+const selectAllTombstonesSQL = `
+	SELECT ` + tombstoneColumnsSQL + ` FROM tombstones ORDER BY first_missing_at
+`
+
+const selectExpiredTombstonesSQL = `
+	SELECT ` + tombstoneColumnsSQL + ` FROM tombstones
+	WHERE first_missing_at <= datetime('now', ?)
+	ORDER BY first_missing_at
+`
+
+const renameTombstoneSQL = `
+	UPDATE tombstones SET full_name = ?, name = ? WHERE full_name = ?
+`
+
+const updateTombstoneArchivePathSQL = `
+	UPDATE tombstones SET archive_path = ? WHERE full_name = ?
+`
+
+const deleteTombstoneSQL = `
+	DELETE FROM tombstones WHERE full_name = ?
+`
+
+// TombstoneRepo replaces the live repos row with a tombstone so the first-missing time is kept
+// while the archive waits out the retention period
+func TombstoneRepo(db *sql.DB, repo model.RepoRecord, archivePath string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(insertTombstoneSQL,
+		repo.FullName, repo.Name, repo.GitHubID, repo.CloneURL, repo.LatestCommitHash, archivePath,
+	); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if _, err := tx.Exec(deleteRepoSQL, repo.FullName); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func GetAllTombstones(db *sql.DB) ([]model.Tombstone, error) {
+	return queryTombstones(db, selectAllTombstonesSQL)
+}
+
+// GetExpiredTombstones returns tombstones older than the retention period
+func GetExpiredTombstones(db *sql.DB, retentionDays int) ([]model.Tombstone, error) {
+	return queryTombstones(db, selectExpiredTombstonesSQL, fmt.Sprintf("-%d days", retentionDays))
+}
+
+func UpdateTombstoneArchivePath(db *sql.DB, fullName, archivePath string) error {
+	_, err := db.Exec(updateTombstoneArchivePathSQL, archivePath, fullName)
+	return err
+}
+
+func DeleteTombstone(db *sql.DB, fullName string) error {
+	_, err := db.Exec(deleteTombstoneSQL, fullName)
+	return err
+}
+
+func queryTombstones(db *sql.DB, query string, args ...any) ([]model.Tombstone, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tombstones []model.Tombstone
+	for rows.Next() {
+		var t model.Tombstone
+		if err := rows.Scan(
+			&t.FullName, &t.Name, &t.GitHubID, &t.CloneURL,
+			&t.LatestCommitHash, &t.ArchivePath, &t.FirstMissingAt,
+		); err != nil {
+			return nil, err
+		}
+		tombstones = append(tombstones, t)
+	}
+
+	return tombstones, rows.Err()
+}
+
+// RenameTombstone points a tombstone at the new name of a repo that was renamed while missing
+func RenameTombstone(db *sql.DB, oldFullName, newFullName, newName string) error {
+	_, err := db.Exec(renameTombstoneSQL, newFullName, newName, oldFullName)
+	return err
+}
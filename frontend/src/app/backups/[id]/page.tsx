@@ -81,6 +81,7 @@ export default async function BackupDetailPage({ params }: { params: Promise<{ i
                 <th>Repository</th>
                 <th>Status</th>
                 <th>Hash</th>
+                <th>Changed refs</th>
                 <th>Size</th>
                 <th>Error</th>
               </tr>
@@ -97,6 +98,12 @@ export default async function BackupDetailPage({ params }: { params: Promise<{ i
                   <td style={{ fontSize: 11, color: "var(--text-muted)", fontFamily: "monospace" }}>
                     {r.commit_hash ? r.commit_hash.slice(0, 8) : "—"}
                   </td>
+                  <td
+                    title={r.changed_refs?.join("\n")}
+                    style={{ fontSize: 11, color: "var(--text-muted)", fontFamily: "monospace", maxWidth: 200, overflow: "hidden", textOverflow: "ellipsis", whiteSpace: "nowrap" }}
+                  >
+                    {r.changed_refs?.length ? r.changed_refs.join(" ") : "—"}
+                  </td>
                   <td style={{ fontSize: 13 }}>{r.archive_size_bytes > 0 ? formatBytes(r.archive_size_bytes) : "—"}</td>
                   <td style={{ color: "var(--danger)", fontSize: 12, maxWidth: 200, overflow: "hidden", textOverflow: "ellipsis", whiteSpace: "nowrap" }}>
                     {r.error_message || "—"}
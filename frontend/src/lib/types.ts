@@ -17,6 +17,7 @@ export interface BackupResult {
   repo_full_name: string;
   status: string;
   commit_hash: string;
+  changed_refs: string[];
   archive_size_bytes: number;
   duration_ms: number;
   error_message: string;
@@ -43,6 +44,8 @@ export interface RepoAnalyticsSnapshot {
   avg_archive_size_bytes: number;
   largest_archive_path: string;
   largest_archive_size_bytes: number;
+  owner_count: number;
+  deleted_archive_count: number;
 }
 
 export interface ExecutionLog {
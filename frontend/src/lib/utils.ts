@@ -50,6 +50,8 @@ export function statusColor(status: string): string {
       return "text-blue-400";
     case "failed":
       return "text-red-400";
+    case "partial":
+      return "text-amber-400";
     case "skipped":
       return "text-zinc-400";
     default:
@@ -65,6 +67,8 @@ export function statusBg(status: string): string {
       return "bg-blue-500/10 border-blue-500/20";
     case "failed":
       return "bg-red-500/10 border-red-500/20";
+    case "partial":
+      return "bg-amber-500/10 border-amber-500/20";
     case "skipped":
       return "bg-zinc-500/10 border-zinc-500/20";
     default:
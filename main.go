@@ -1,6 +1,9 @@
 package main
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/MishraShardendu22/github-backup/config"
 	"github.com/MishraShardendu22/github-backup/database"
 	"github.com/MishraShardendu22/github-backup/service"
@@ -28,7 +31,25 @@ func main() {
 	}
 	defer monitor.Close()
 
-	logger.Info("Worker started")
+	command := ""
+	if len(os.Args) > 1 {
+		command = os.Args[1]
+	}
 
-	service.RunBackupFlow(cfg, db)
+	switch command {
+	case "":
+		logger.Info("Worker started")
+		service.RunBackupFlow(cfg, db)
+	case "confirm-deletions":
+		logger.Info("Confirming pending deletions")
+		util.ErrorHandler(service.ConfirmPendingDeletions(cfg, db))
+	case "preflight":
+		logger.Info("Running preflight checks")
+		util.ErrorHandler(service.RunPreflight(cfg))
+	case "migration-archive":
+		logger.Info("Exporting organization migration archives")
+		util.ErrorHandler(service.RunMigrationArchives(cfg, db))
+	default:
+		util.ErrorHandler(fmt.Errorf("unknown command %q (available: confirm-deletions, preflight, migration-archive)", command))
+	}
 }
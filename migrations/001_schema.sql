@@ -24,6 +24,8 @@ CREATE TABLE IF NOT EXISTS backup_results (
     error_message TEXT DEFAULT '',
     created_at TIMESTAMPTZ DEFAULT NOW()
 );
+-- Refs that triggered the backup: +ref created, ~ref moved, -ref deleted
+ALTER TABLE backup_results ADD COLUMN IF NOT EXISTS changed_refs TEXT[] NOT NULL DEFAULT '{}';
 
 -- Execution logs from worker
 CREATE TABLE IF NOT EXISTS execution_logs (
@@ -6,13 +6,51 @@ import (
 )
 
 type ConfigModel struct {
-	OrgAccount          string
-	MainAccount         string
-	BackupRepoPath      string
-	DBPath              string
-	ProjectAccount      string
-	GitHubTokenPrivate  string
-	GitHubTokenPersonal string
+	OrgAccount           string
+	MainAccount          string
+	BackupRepoPath       string
+	DBPath               string
+	ProjectAccount       string
+	GitHubTokenPrivate   string
+	GitHubTokenPersonal  string
+	GitHubAPIURL         string
+	GitCloneHost         string
+	GitHubApp            *GitHubApp
+	DeletionMaxCount     int
+	DeletionMaxPercent   float64
+	DeletedRetentionDays int
+	PreflightMinFreeGB   int
+	BackupWikis          bool
+	ExportIssues         bool
+	BackupReleases       bool
+	ReleaseAssetPolicy   string
+	SnapshotGovernance   bool
+	MigrationPollSeconds int
+	MigrationTimeout     int
+	BackupMode           string
+	BackupModeRules      []BackupModeRule
+	MirrorIncrementals   int
+	MirrorFullDays       int
+	MirrorCacheDir       string
+	MirrorCacheMaxGB     int
+	APIHashCheck         bool
+	Filters              FilterRules
+	Sources              []Source
+}
+
+// Backup modes decide what is stored for a repo: a snapshot tarball of the default branch's working tree,
+// a git bundle of the full history with every branch and tag, or both
+const (
+	BackupModeSnapshot = "snapshot"
+	BackupModeMirror   = "mirror"
+	BackupModeBoth     = "both"
+)
+
+// BackupModeRule sets the backup mode of the repos whose full name or name matches Pattern, a glob or a
+// regex prefixed with re: as in the filter rules
+type BackupModeRule struct {
+	Pattern string
+	Mode    string
 }
 
 type Repos struct {
@@ -32,7 +70,9 @@ type Repo struct {
 	Private         bool     `json:"private"`
 	Archived        bool     `json:"archived"`
 	Disabled        bool     `json:"disabled"`
+	HasWiki         bool     `json:"has_wiki"`
 	ID              int      `json:"id"`
+	Size            int      `json:"size"`
 	ForksCount      int      `json:"forks_count"`
 	WatchersCount   int      `json:"watchers_count"`
 	StargazersCount int      `json:"stargazers_count"`
@@ -52,22 +92,95 @@ type Repo struct {
 	Description     string   `json:"description"`
 	DefaultBranch   string   `json:"default_branch"`
 	Topics          []string `json:"topics"`
+	// WikiOf is set on the synthetic <repo>.wiki entries the worker adds for repos with a wiki
+	WikiOf string `json:"wiki_of,omitempty"`
+	// Source and Provider name the configured source that discovered the repo; they are not part of the API payload
+	Source   string `json:"-"`
+	Provider string `json:"-"`
+}
+
+// GitHubID is the repo's GitHub repository ID, or 0 for repos from other forges whose IDs live in their own space
+func (r Repo) GitHubID() int {
+	if r.Provider != "" && r.Provider != ProviderGitHub {
+		return 0
+	}
+	return r.ID
 }
 
 type RepoRecord struct {
 	ID               int
+	GitHubID         int
+	SizeKB           int
+	Fork             bool
+	Archived         bool
 	Name             string
 	FullName         string
 	CloneURL         string
 	LatestCommitHash string
+	Description      string
+	Language         string
+	Visibility       string
+	DefaultBranch    string
+	PushedAt         string
+	Topics           []string
 	LastBackedUpAt   sql.NullTime
 	CreatedAt        time.Time
 	UpdatedAt        time.Time
 }
 
+// RepoDescriptor is the part of a repo's metadata kept in repo_metadata_history. Counters and push times
+// change on every star or push and live only in the repos columns.
+type RepoDescriptor struct {
+	FullName      string   `json:"full_name"`
+	Owner         string   `json:"owner"`
+	Name          string   `json:"name"`
+	Description   string   `json:"description"`
+	Topics        []string `json:"topics"`
+	Visibility    string   `json:"visibility"`
+	DefaultBranch string   `json:"default_branch"`
+	Language      string   `json:"language"`
+	Archived      bool     `json:"archived"`
+	Fork          bool     `json:"fork"`
+}
+
+// Descriptor returns the descriptive metadata tracked in the repo's history
+func (r Repo) Descriptor() RepoDescriptor {
+	return RepoDescriptor{
+		FullName:      r.FullName,
+		Owner:         r.Owner.Login,
+		Name:          r.Name,
+		Description:   r.Description,
+		Topics:        r.Topics,
+		Visibility:    r.Visibility,
+		DefaultBranch: r.DefaultBranch,
+		Language:      r.Language,
+		Archived:      r.Archived,
+		Fork:          r.Fork,
+	}
+}
+
+// RepoMetadataFile is the document written next to each archive as <repo>.metadata.json
+type RepoMetadataFile struct {
+	BackedUpAt time.Time `json:"backed_up_at"`
+	CommitHash string    `json:"commit_hash"`
+	Repository Repo      `json:"repository"`
+}
+
 type RepoStats struct {
 	TotalRepos    int
 	BackedUpRepos int
 	FailedRepos   int
 	LastRunAt     sql.NullTime
 }
+
+// Tombstone tracks a repo that disappeared upstream; its archive lives under _Repos/_deleted
+// until the retention period passes
+type Tombstone struct {
+	GitHubID         int
+	Name             string
+	FullName         string
+	CloneURL         string
+	LatestCommitHash string
+	ArchivePath      string
+	FirstMissingAt   time.Time
+}
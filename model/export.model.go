@@ -0,0 +1,15 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ExportRecord is one object from an issue, pull request, comment, review, label or milestone listing.
+// Raw keeps the API payload verbatim; the decoded fields are only what the exporter merges and pages by.
+type ExportRecord struct {
+	ID        int64           `json:"id"`
+	Number    int             `json:"number"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	Raw       json.RawMessage `json:"-"`
+}
@@ -0,0 +1,19 @@
+package model
+
+// FilterRules decides which discovered repositories are backed up.
+// Name patterns are globs matched against both the full name and the short name,
+// or regular expressions when prefixed with "re:".
+type FilterRules struct {
+	SkipForks        bool     `json:"skip_forks"`
+	SkipArchived     bool     `json:"skip_archived"`
+	SkipDisabled     bool     `json:"skip_disabled"`
+	IncludeOwners    []string `json:"include_owners"`
+	ExcludeOwners    []string `json:"exclude_owners"`
+	IncludeNames     []string `json:"include_names"`
+	ExcludeNames     []string `json:"exclude_names"`
+	Visibilities     []string `json:"visibility"`
+	IncludeLanguages []string `json:"include_languages"`
+	ExcludeLanguages []string `json:"exclude_languages"`
+	IncludeTopics    []string `json:"include_topics"`
+	ExcludeTopics    []string `json:"exclude_topics"`
+}
@@ -0,0 +1,20 @@
+package model
+
+// Gist is the part of GitHub's gist payload the worker needs to clone and describe a gist
+type Gist struct {
+	ID          string              `json:"id"`
+	Description string              `json:"description"`
+	Public      bool                `json:"public"`
+	Owner       Owner               `json:"owner"`
+	GitPullURL  string              `json:"git_pull_url"`
+	HTMLURL     string              `json:"html_url"`
+	CreatedAt   string              `json:"created_at"`
+	UpdatedAt   string              `json:"updated_at"`
+	Files       map[string]GistFile `json:"files"`
+}
+
+type GistFile struct {
+	Filename string `json:"filename"`
+	Language string `json:"language"`
+	Size     int    `json:"size"`
+}
@@ -0,0 +1,81 @@
+package model
+
+// GovernanceFormatVersion is written into every governance snapshot file and bumped whenever their layout changes
+const GovernanceFormatVersion = 1
+
+// OrgGovernance is _governance/<org>/org.json. Sections the token may not read are left empty and
+// named in Unavailable with the status GitHub answered, so a lost permission is not mistaken for an empty list.
+// Webhooks and Rulesets are kept as GitHub returns them, minus webhook secrets and delivery status.
+type OrgGovernance struct {
+	FormatVersion        int               `json:"format_version"`
+	Org                  string            `json:"org"`
+	Members              []OrgMember       `json:"members"`
+	OutsideCollaborators []OrgMember       `json:"outside_collaborators"`
+	Teams                []Team            `json:"teams"`
+	Webhooks             []any             `json:"webhooks"`
+	Rulesets             []any             `json:"rulesets"`
+	ActionsSecrets       []SecretName      `json:"actions_secrets"`
+	Unavailable          map[string]string `json:"unavailable,omitempty"`
+}
+
+type OrgMember struct {
+	Login string `json:"login"`
+	ID    int    `json:"id"`
+	Role  string `json:"role,omitempty"`
+}
+
+type Team struct {
+	ID          int        `json:"id"`
+	Slug        string     `json:"slug"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Privacy     string     `json:"privacy"`
+	Permission  string     `json:"permission"`
+	Parent      string     `json:"parent,omitempty"`
+	Maintainers []string   `json:"maintainers"`
+	Members     []string   `json:"members"`
+	Repos       []TeamRepo `json:"repos"`
+}
+
+type TeamRepo struct {
+	FullName string `json:"full_name"`
+	RoleName string `json:"role_name"`
+}
+
+// RepoGovernance is _governance/<org>/repos/<repo>.json. BranchProtection is keyed by branch name.
+type RepoGovernance struct {
+	FormatVersion    int               `json:"format_version"`
+	Repo             string            `json:"repo"`
+	Collaborators    []Collaborator    `json:"collaborators"`
+	BranchProtection map[string]any    `json:"branch_protection"`
+	Rulesets         []any             `json:"rulesets"`
+	Webhooks         []any             `json:"webhooks"`
+	DeployKeys       []DeployKey       `json:"deploy_keys"`
+	ActionsSecrets   []SecretName      `json:"actions_secrets"`
+	Unavailable      map[string]string `json:"unavailable,omitempty"`
+}
+
+type Collaborator struct {
+	Login       string          `json:"login"`
+	ID          int             `json:"id"`
+	RoleName    string          `json:"role_name"`
+	Permissions map[string]bool `json:"permissions"`
+}
+
+// DeployKey keeps the SHA256 fingerprint of a deploy key instead of the key itself
+type DeployKey struct {
+	ID          int    `json:"id"`
+	Title       string `json:"title"`
+	ReadOnly    bool   `json:"read_only"`
+	Verified    bool   `json:"verified"`
+	Fingerprint string `json:"fingerprint"`
+	CreatedAt   string `json:"created_at"`
+}
+
+// SecretName is an Actions secret without its value, which the API never returns anyway
+type SecretName struct {
+	Name       string `json:"name"`
+	Visibility string `json:"visibility,omitempty"`
+	CreatedAt  string `json:"created_at"`
+	UpdatedAt  string `json:"updated_at"`
+}
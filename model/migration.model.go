@@ -0,0 +1,36 @@
+package model
+
+// Migration is the part of GitHub's organization migration payload the worker polls
+type Migration struct {
+	ID                 int64  `json:"id"`
+	GUID               string `json:"guid"`
+	State              string `json:"state"`
+	LockRepositories   bool   `json:"lock_repositories"`
+	ExcludeAttachments bool   `json:"exclude_attachments"`
+	Repositories       []Repo `json:"repositories"`
+	CreatedAt          string `json:"created_at"`
+	UpdatedAt          string `json:"updated_at"`
+}
+
+// Migration states GitHub reports while an archive is prepared
+const (
+	MigrationStatePending   = "pending"
+	MigrationStateExporting = "exporting"
+	MigrationStateExported  = "exported"
+	MigrationStateFailed    = "failed"
+)
+
+// MigrationRecord is written next to each downloaded migration archive as <archive>.json. Archives too
+// large for a git blob are split; concatenating Parts in order restores the archive with the given SHA256.
+type MigrationRecord struct {
+	ID           int64    `json:"id"`
+	GUID         string   `json:"guid"`
+	Org          string   `json:"org"`
+	Repositories []string `json:"repositories"`
+	StartedAt    string   `json:"started_at"`
+	ExportedAt   string   `json:"exported_at"`
+	SizeBytes    int64    `json:"size_bytes"`
+	SHA256       string   `json:"sha256"`
+	Archive      string   `json:"archive"`
+	Parts        []string `json:"parts,omitempty"`
+}
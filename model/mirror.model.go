@@ -0,0 +1,42 @@
+package model
+
+import "time"
+
+// MirrorFormatVersion is bumped whenever the layout of <repo>.mirror changes incompatibly
+const MirrorFormatVersion = 2
+
+// Mirror bundle kinds: a full bundle holds every object, an incremental one only the commits since the
+// refs of the previous bundle in the chain
+const (
+	MirrorBundleFull        = "full"
+	MirrorBundleIncremental = "incremental"
+)
+
+// MirrorBundle is one git bundle of a repo's chain. Bundles too large for a git blob are split; concatenating
+// Parts in order restores Name, whose SHA-256 is recorded.
+type MirrorBundle struct {
+	Name      string    `json:"name"`
+	Kind      string    `json:"kind"`
+	CreatedAt time.Time `json:"created_at"`
+	SizeBytes int64     `json:"size_bytes"`
+	SHA256    string    `json:"sha256"`
+	Parts     []string  `json:"parts,omitempty"`
+}
+
+// MirrorManifest is written as <owner>/<repo>.mirror/bundle.json. Fetching Bundles in order into an empty
+// repository and then setting Refs restores the repo as of UpdatedAt, deleted branches and tags included.
+type MirrorManifest struct {
+	FormatVersion int               `json:"format_version"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+	Head          string            `json:"head"`
+	Refs          map[string]string `json:"refs"`
+	Bundles       []MirrorBundle    `json:"bundles"`
+}
+
+// MirrorState is what the mirror_states table keeps per repo: the refs of the last pushed mirror backup,
+// which the next incremental bundle is built on, and the chain it extends
+type MirrorState struct {
+	FullName string
+	Refs     map[string]string
+	Bundles  []MirrorBundle
+}
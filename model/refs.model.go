@@ -0,0 +1,18 @@
+package model
+
+// RepoRefs is what the repo_refs table keeps per repo: the branches, tags and HEAD of the last pushed backup
+// and their fingerprint, which the hash check compares against the remote. PushedAt is the forge's push
+// time the refs were listed at; while it is unchanged the remote does not have to be asked again.
+type RepoRefs struct {
+	FullName    string
+	Fingerprint string
+	PushedAt    string
+	Refs        map[string]string
+}
+
+// RepoPushState is what the GitHub API reports about a repo's last push, from the listing's pushed_at or a
+// GraphQL lookup. DefaultBranchOID is only known from GraphQL.
+type RepoPushState struct {
+	PushedAt         string
+	DefaultBranchOID string
+}
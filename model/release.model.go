@@ -0,0 +1,64 @@
+package model
+
+// Release is the part of GitHub's release payload kept in <owner>/<repo>.releases/releases.json.
+// Download counts are left out on purpose so the file only changes when a release does.
+type Release struct {
+	ID              int            `json:"id"`
+	TagName         string         `json:"tag_name"`
+	TargetCommitish string         `json:"target_commitish"`
+	Name            string         `json:"name"`
+	Body            string         `json:"body"`
+	Draft           bool           `json:"draft"`
+	Prerelease      bool           `json:"prerelease"`
+	Author          Owner          `json:"author"`
+	HTMLURL         string         `json:"html_url"`
+	CreatedAt       string         `json:"created_at"`
+	PublishedAt     string         `json:"published_at"`
+	Assets          []ReleaseAsset `json:"assets"`
+}
+
+type ReleaseAsset struct {
+	ID                 int    `json:"id"`
+	Name               string `json:"name"`
+	Label              string `json:"label"`
+	ContentType        string `json:"content_type"`
+	State              string `json:"state"`
+	Size               int64  `json:"size"`
+	URL                string `json:"url"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+	CreatedAt          string `json:"created_at"`
+	UpdatedAt          string `json:"updated_at"`
+	// Backup records what the worker did with the asset; it is not part of the API payload
+	Backup *AssetBackup `json:"backup,omitempty"`
+}
+
+// Release asset policies decide what happens to asset binaries
+const (
+	// AssetPolicyStore downloads assets that fit in a single git blob and records larger ones only
+	AssetPolicyStore = "store"
+	// AssetPolicySplit downloads every asset and splits the ones too large for a git blob into parts
+	AssetPolicySplit = "split"
+	// AssetPolicyRecord never downloads assets and keeps only their metadata
+	AssetPolicyRecord = "record"
+)
+
+// AssetBackup is where an asset's binary lives inside <owner>/<repo>.releases, relative to that directory.
+// Split assets list their parts in order; concatenating them restores the file with the given SHA256.
+type AssetBackup struct {
+	Status string   `json:"status"`
+	Path   string   `json:"path,omitempty"`
+	Parts  []string `json:"parts,omitempty"`
+	SHA256 string   `json:"sha256,omitempty"`
+	Reason string   `json:"reason,omitempty"`
+}
+
+// ReleaseAssetRecord is the SQLite bookkeeping that lets unchanged assets be skipped by asset ID
+type ReleaseAssetRecord struct {
+	AssetID   int
+	ReleaseID int
+	FullName  string
+	Name      string
+	Size      int64
+	UpdatedAt string
+	Backup    AssetBackup
+}
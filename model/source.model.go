@@ -0,0 +1,86 @@
+package model
+
+import "strings"
+
+const (
+	ProviderGitHub = "github"
+	ProviderGitLab = "gitlab"
+	ProviderGitea  = "gitea"
+	// ProviderGit backs up an explicit list of git remotes that have no forge API behind them
+	ProviderGit = "git"
+)
+
+const (
+	SourceKindOrg               = "org"
+	SourceKindUser              = "user"
+	SourceKindAuthenticatedUser = "authenticated-user"
+	// SourceKindInstallation lists every repository a GitHub App installation can access
+	SourceKindInstallation = "installation"
+	// SourceKindStarred lists the repositories starred by Account, or by the token owner without one
+	SourceKindStarred = "starred"
+	// SourceKindGists lists the gists of Account, or every gist of the token owner (secret ones included) without one
+	SourceKindGists = "gists"
+	// SourceKindRemotes is the only kind of a git provider source
+	SourceKindRemotes = "remotes"
+)
+
+// Remote is one explicitly listed repository of a git provider source. Name becomes the repo's full
+// name and archive path; it defaults to <host>/<path> of the URL.
+type Remote struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// GitHubApp authenticates a source as a GitHub App installation instead of with a static token.
+// The private key is read from PrivateKeyEnv when set, otherwise from PrivateKeyFile.
+type GitHubApp struct {
+	AppID          int64  `json:"app_id"`
+	InstallationID int64  `json:"installation_id"`
+	PrivateKeyFile string `json:"private_key_file"`
+	PrivateKeyEnv  string `json:"private_key_env"`
+}
+
+// Source is one account the worker discovers repositories from.
+// Kind is one of the SourceKind* constants; Account is ignored for authenticated-user sources,
+// which list everything the token can see. Filters apply to this source only, on top of the global rules.
+// Provider is one of the Provider* constants (github by default). For GitLab an org source is a group.
+// APIURL and CloneHost point the source at GitHub Enterprise Server, a self-hosted forge or a local fake;
+// CloneHost is either an SSH host alias (git@host, cloned as git@host:owner/repo.git) or a URL prefix such
+// as https://ghe.example.com, ssh://git@host:2222 or file:///srv/git. Namespace, when set, is prepended to
+// the full name of every repo from this source so mirrors of the same repos on another forge get their own
+// archives and SQLite rows. App switches a GitHub source to installation tokens, which are also used to
+// clone over HTTPS. Remotes lists the repositories of a git provider source. Mode overrides BACKUP_MODE for
+// the repos of this source.
+type Source struct {
+	Name      string       `json:"name"`
+	Provider  string       `json:"provider"`
+	Kind      string       `json:"kind"`
+	Account   string       `json:"account"`
+	Token     string       `json:"token"`
+	TokenEnv  string       `json:"token_env"`
+	Type      string       `json:"type"`
+	APIURL    string       `json:"api_url"`
+	CloneHost string       `json:"clone_host"`
+	Namespace string       `json:"namespace"`
+	App       *GitHubApp   `json:"app"`
+	Remotes   []Remote     `json:"remotes"`
+	Filters   *FilterRules `json:"filters"`
+	Mode      string       `json:"mode"`
+}
+
+// SourceCount is how many repositories a source contributed to a run
+type SourceCount struct {
+	Name       string
+	Kind       string
+	Discovered int
+	Excluded   int
+	Failed     bool
+}
+
+// RemoteName is the repo's full name on the forge itself, without the source namespace
+func (s Source) RemoteName(fullName string) string {
+	if s.Namespace == "" {
+		return fullName
+	}
+	return strings.TrimPrefix(fullName, s.Namespace+"/")
+}
@@ -1,7 +0,0 @@
-package model
-
-type URL struct {
-	GetAllOrgRepos     string
-	GetAllPublicRepos  string
-	GetAllPrivateRepos string
-}
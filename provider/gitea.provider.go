@@ -0,0 +1,74 @@
+package provider
+
+import (
+	"encoding/json"
+
+	"github.com/MishraShardendu22/github-backup/model"
+)
+
+// Gitea covers Gitea and Forgejo. Their repository payload is close enough to GitHub's that it
+// decodes straight into model.Repo; only the listing endpoints and paging differ.
+type Gitea struct{}
+
+func (Gitea) ListRepos(source model.Source) ([]model.Repo, error) {
+	var allRepos []model.Repo
+	err := listPages(giteaReposURL(source), source.Token, func(body []byte) (int, error) {
+		var repos []model.Repo
+		if err := json.Unmarshal(body, &repos); err != nil {
+			return 0, err
+		}
+		for _, repo := range repos {
+			allRepos = append(allRepos, normalizeGiteaRepo(repo))
+		}
+		return len(repos), nil
+	})
+
+	return allRepos, err
+}
+
+func (Gitea) CloneURL(source model.Source, repo model.Repo) string {
+	return cloneURL(source, repo)
+}
+
+func (Gitea) FetchMetadata(source model.Source, fullName string) (model.Repo, error) {
+	var repo model.Repo
+	body, err := getJSON(source.APIURL+"/repos/"+fullName, source.Token)
+	if err != nil {
+		return repo, err
+	}
+
+	if err := json.Unmarshal(body, &repo); err != nil {
+		return repo, err
+	}
+	return normalizeGiteaRepo(repo), nil
+}
+
+func giteaReposURL(source model.Source) string {
+	switch source.Kind {
+	case model.SourceKindOrg:
+		return source.APIURL + "/orgs/" + source.Account + "/repos?limit=50&page="
+	case model.SourceKindUser:
+		return source.APIURL + "/users/" + source.Account + "/repos?limit=50&page="
+	case model.SourceKindStarred:
+		if source.Account != "" {
+			return source.APIURL + "/users/" + source.Account + "/starred?limit=50&page="
+		}
+		return source.APIURL + "/user/starred?limit=50&page="
+	default:
+		return source.APIURL + "/user/repos?limit=50&page="
+	}
+}
+
+// normalizeGiteaRepo fills the fields Gitea leaves out of its payload
+func normalizeGiteaRepo(repo model.Repo) model.Repo {
+	if repo.Visibility == "" {
+		repo.Visibility = "public"
+		if repo.Private {
+			repo.Visibility = "private"
+		}
+	}
+	if repo.PushedAt == "" {
+		repo.PushedAt = repo.UpdatedAt
+	}
+	return repo
+}
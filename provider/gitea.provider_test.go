@@ -0,0 +1,100 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/MishraShardendu22/github-backup/model"
+)
+
+func TestGiteaListRepos(t *testing.T) {
+	repo := func(id int, fullName string, private bool) string {
+		return fmt.Sprintf(`{"id":%d,"name":"%s","full_name":"%s","private":%t,"ssh_url":"git@gitea.example.com:%s.git",
+			"updated_at":"2026-10-0%dT10:00:00Z","owner":{"login":"acme"}}`,
+			id, fullName[strings.Index(fullName, "/")+1:], fullName, private, fullName, id)
+	}
+	pages := []string{
+		"[" + repo(1, "acme/api", false) + "," + repo(2, "acme/web", true) + "]",
+		"[" + repo(3, "acme/docs", false) + "]",
+	}
+
+	tests := []struct {
+		name   string
+		source model.Source
+		path   string
+	}{
+		{name: "org", source: model.Source{Kind: model.SourceKindOrg, Account: "acme"}, path: "/orgs/acme/repos"},
+		{name: "user", source: model.Source{Kind: model.SourceKindUser, Account: "octo"}, path: "/users/octo/repos"},
+		{name: "authenticated user", source: model.Source{Kind: model.SourceKindAuthenticatedUser}, path: "/user/repos"},
+		{name: "starred by account", source: model.Source{Kind: model.SourceKindStarred, Account: "octo"}, path: "/users/octo/starred"},
+		{name: "starred by token owner", source: model.Source{Kind: model.SourceKindStarred}, path: "/user/starred"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, requests := fakeListing(t, tt.path, pages)
+			source := tt.source
+			source.Provider = model.ProviderGitea
+			source.Token = "test-token"
+			source.APIURL = srv.URL
+
+			repos, err := Gitea{}.ListRepos(source)
+			if err != nil {
+				t.Fatalf("ListRepos: %v", err)
+			}
+
+			var names []string
+			for _, repo := range repos {
+				names = append(names, repo.FullName)
+			}
+			if strings.Join(names, " ") != "acme/api acme/web acme/docs" {
+				t.Errorf("repos = %v", names)
+			}
+			if len(*requests) != 3 {
+				t.Fatalf("requests = %v, want pages 1 to 3", *requests)
+			}
+			for i, request := range *requests {
+				if want := fmt.Sprintf("%s?limit=50&page=%d", tt.path, i+1); request != want {
+					t.Errorf("request %d = %s, want %s", i, request, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGiteaNormalizesRepos(t *testing.T) {
+	srv, _ := fakeListing(t, "/user/repos", []string{`[
+		{"id":1,"full_name":"acme/api","private":true,"updated_at":"2026-10-01T10:00:00Z"},
+		{"id":2,"full_name":"acme/web","private":false,"updated_at":"2026-10-02T10:00:00Z"},
+		{"id":3,"full_name":"acme/docs","private":true,"visibility":"limited","updated_at":"2026-10-03T10:00:00Z","pushed_at":"2026-09-30T10:00:00Z"}
+	]`})
+
+	repos, err := Gitea{}.ListRepos(model.Source{Token: "test-token", APIURL: srv.URL})
+	if err != nil || len(repos) != 3 {
+		t.Fatalf("ListRepos = %v, %v", repos, err)
+	}
+
+	want := []struct{ visibility, pushedAt string }{
+		{"private", "2026-10-01T10:00:00Z"},
+		{"public", "2026-10-02T10:00:00Z"},
+		{"limited", "2026-09-30T10:00:00Z"},
+	}
+	for i, repo := range repos {
+		if repo.Visibility != want[i].visibility || repo.PushedAt != want[i].pushedAt {
+			t.Errorf("%s: visibility %q pushed_at %q, want %q %q",
+				repo.FullName, repo.Visibility, repo.PushedAt, want[i].visibility, want[i].pushedAt)
+		}
+	}
+}
+
+func TestGiteaCloneURL(t *testing.T) {
+	repo := model.Repo{FullName: "mirror/acme/api", SSHURL: "git@gitea.example.com:acme/api.git"}
+
+	if got := (Gitea{}).CloneURL(model.Source{}, repo); got != repo.SSHURL {
+		t.Errorf("CloneURL without clone host = %s, want the reported SSH URL", got)
+	}
+	source := model.Source{CloneHost: "ssh://git@gitea.example.com:2222", Namespace: "mirror"}
+	if got, want := (Gitea{}).CloneURL(source, repo), "ssh://git@gitea.example.com:2222/acme/api.git"; got != want {
+		t.Errorf("CloneURL = %s, want %s", got, want)
+	}
+}
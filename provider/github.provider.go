@@ -0,0 +1,134 @@
+package provider
+
+import (
+	"encoding/json"
+
+	"github.com/MishraShardendu22/github-backup/controller"
+	"github.com/MishraShardendu22/github-backup/model"
+	"github.com/MishraShardendu22/github-backup/service/helper"
+)
+
+// GitHub covers github.com and GitHub Enterprise Server
+type GitHub struct{}
+
+func (GitHub) ListRepos(source model.Source) ([]model.Repo, error) {
+	token, err := controller.SourceToken(source)
+	if err != nil {
+		return nil, err
+	}
+
+	switch source.Kind {
+	case model.SourceKindAuthenticatedUser:
+		return controller.RepoControllerPrivate(githubReposURL(source), token)
+	case model.SourceKindStarred:
+		if source.Account == "" {
+			return controller.RepoControllerPrivate(githubReposURL(source), token)
+		}
+		return controller.RepoController(githubReposURL(source), token)
+	case model.SourceKindInstallation:
+		return controller.InstallationRepoController(githubReposURL(source), token)
+	case model.SourceKindGists:
+		gists, err := controller.GistController(githubReposURL(source), token)
+		repos := make([]model.Repo, 0, len(gists))
+		for _, gist := range gists {
+			repos = append(repos, gistRepo(gist))
+		}
+		return repos, err
+	default:
+		return controller.RepoController(githubReposURL(source), token)
+	}
+}
+
+// CloneURL uses the clone host, since GitHub's ssh_url ignores the SSH alias holding the project key.
+// Gists are cloned from their git_pull_url, which works without credentials for public and secret gists.
+func (GitHub) CloneURL(source model.Source, repo model.Repo) string {
+	if source.Kind == model.SourceKindGists && repo.CloneURL != "" {
+		return repo.CloneURL
+	}
+	return helper.BuildCloneURL(source.CloneHost, source.RemoteName(repo.FullName))
+}
+
+func (GitHub) FetchMetadata(source model.Source, fullName string) (model.Repo, error) {
+	var repo model.Repo
+	token, err := controller.SourceToken(source)
+	if err != nil {
+		return repo, err
+	}
+
+	if _, gistID, ok := helper.ParseGistFullName(fullName); ok {
+		body, err := getJSON(source.APIURL+"/gists/"+gistID, token)
+		if err != nil {
+			return repo, err
+		}
+
+		var gist model.Gist
+		if err := json.Unmarshal(body, &gist); err != nil {
+			return repo, err
+		}
+		return gistRepo(gist), nil
+	}
+
+	body, err := getJSON(source.APIURL+"/repos/"+fullName, token)
+	if err != nil {
+		return repo, err
+	}
+
+	err = json.Unmarshal(body, &repo)
+	return repo, err
+}
+
+// gistRepo maps a gist onto a repo record tracked as gists/<owner>/<id>. Gist IDs are not numeric,
+// so ID stays 0 and gists are never matched by rename detection.
+func gistRepo(gist model.Gist) model.Repo {
+	repo := model.Repo{
+		Name:        gist.ID,
+		FullName:    helper.GistFullName(gist.Owner.Login, gist.ID),
+		Description: gist.Description,
+		Private:     !gist.Public,
+		Visibility:  "public",
+		Owner:       gist.Owner,
+		CloneURL:    gist.GitPullURL,
+		HTMLURL:     gist.HTMLURL,
+		CreatedAt:   gist.CreatedAt,
+		UpdatedAt:   gist.UpdatedAt,
+		PushedAt:    gist.UpdatedAt,
+	}
+	if !gist.Public {
+		repo.Visibility = "secret"
+	}
+
+	size := 0
+	for _, file := range gist.Files {
+		size += file.Size
+		if repo.Language == "" {
+			repo.Language = file.Language
+		}
+	}
+	repo.Size = size / 1024
+
+	return repo
+}
+
+// githubReposURL is the paginated listing endpoint for a source; the page number is appended by the caller
+func githubReposURL(source model.Source) string {
+	switch source.Kind {
+	case model.SourceKindOrg:
+		return source.APIURL + "/orgs/" + source.Account + "/repos?type=" + source.Type + "&per_page=50&page="
+	case model.SourceKindUser:
+		return source.APIURL + "/users/" + source.Account + "/repos?type=" + source.Type + "&per_page=50&page="
+	case model.SourceKindInstallation:
+		return source.APIURL + "/installation/repositories?per_page=100&page="
+	case model.SourceKindStarred:
+		if source.Account != "" {
+			return source.APIURL + "/users/" + source.Account + "/starred?per_page=100&page="
+		}
+		return source.APIURL + "/user/starred?per_page=100&page="
+	case model.SourceKindGists:
+		if source.Account != "" {
+			return source.APIURL + "/users/" + source.Account + "/gists?per_page=100&page="
+		}
+		return source.APIURL + "/gists?per_page=100&page="
+	default:
+		return source.APIURL + "/user/repos?type=" + source.Type + "&per_page=100&page="
+	}
+}
@@ -0,0 +1,127 @@
+package provider
+
+import (
+	"encoding/json"
+	"net/url"
+
+	"github.com/MishraShardendu22/github-backup/model"
+)
+
+// GitLab covers gitlab.com and self-managed GitLab. Org sources are groups (subgroups included)
+// and a project's full name is its path with namespace, so archives keep the group hierarchy.
+type GitLab struct{}
+
+type gitlabProject struct {
+	ID                int      `json:"id"`
+	Name              string   `json:"name"`
+	PathWithNamespace string   `json:"path_with_namespace"`
+	Description       string   `json:"description"`
+	Visibility        string   `json:"visibility"`
+	Archived          bool     `json:"archived"`
+	WikiEnabled       bool     `json:"wiki_enabled"`
+	DefaultBranch     string   `json:"default_branch"`
+	SSHURLToRepo      string   `json:"ssh_url_to_repo"`
+	HTTPURLToRepo     string   `json:"http_url_to_repo"`
+	WebURL            string   `json:"web_url"`
+	CreatedAt         string   `json:"created_at"`
+	LastActivityAt    string   `json:"last_activity_at"`
+	StarCount         int      `json:"star_count"`
+	ForksCount        int      `json:"forks_count"`
+	OpenIssuesCount   int      `json:"open_issues_count"`
+	Topics            []string `json:"topics"`
+	TagList           []string `json:"tag_list"`
+	ForkedFromProject *struct {
+		ID int `json:"id"`
+	} `json:"forked_from_project"`
+	Namespace struct {
+		ID       int    `json:"id"`
+		Kind     string `json:"kind"`
+		FullPath string `json:"full_path"`
+		WebURL   string `json:"web_url"`
+	} `json:"namespace"`
+}
+
+func (GitLab) ListRepos(source model.Source) ([]model.Repo, error) {
+	var allRepos []model.Repo
+	err := listPages(gitlabProjectsURL(source), source.Token, func(body []byte) (int, error) {
+		var projects []gitlabProject
+		if err := json.Unmarshal(body, &projects); err != nil {
+			return 0, err
+		}
+		for _, project := range projects {
+			allRepos = append(allRepos, project.toRepo())
+		}
+		return len(projects), nil
+	})
+
+	return allRepos, err
+}
+
+func (GitLab) CloneURL(source model.Source, repo model.Repo) string {
+	return cloneURL(source, repo)
+}
+
+func (GitLab) FetchMetadata(source model.Source, fullName string) (model.Repo, error) {
+	body, err := getJSON(source.APIURL+"/projects/"+url.PathEscape(fullName), source.Token)
+	if err != nil {
+		return model.Repo{}, err
+	}
+
+	var project gitlabProject
+	if err := json.Unmarshal(body, &project); err != nil {
+		return model.Repo{}, err
+	}
+	return project.toRepo(), nil
+}
+
+func gitlabProjectsURL(source model.Source) string {
+	switch source.Kind {
+	case model.SourceKindOrg:
+		return source.APIURL + "/groups/" + url.PathEscape(source.Account) + "/projects?include_subgroups=true&per_page=100&page="
+	case model.SourceKindUser:
+		return source.APIURL + "/users/" + url.PathEscape(source.Account) + "/projects?per_page=100&page="
+	case model.SourceKindStarred:
+		if source.Account != "" {
+			return source.APIURL + "/users/" + url.PathEscape(source.Account) + "/starred_projects?per_page=100&page="
+		}
+		return source.APIURL + "/projects?starred=true&per_page=100&page="
+	default:
+		return source.APIURL + "/projects?membership=true&per_page=100&page="
+	}
+}
+
+func (p gitlabProject) toRepo() model.Repo {
+	topics := p.Topics
+	if len(topics) == 0 {
+		topics = p.TagList
+	}
+
+	return model.Repo{
+		ID:              p.ID,
+		Name:            p.Name,
+		FullName:        p.PathWithNamespace,
+		Description:     p.Description,
+		Visibility:      p.Visibility,
+		Private:         p.Visibility != "public",
+		Archived:        p.Archived,
+		HasWiki:         p.WikiEnabled,
+		Fork:            p.ForkedFromProject != nil,
+		DefaultBranch:   p.DefaultBranch,
+		SSHURL:          p.SSHURLToRepo,
+		CloneURL:        p.HTTPURLToRepo,
+		HTMLURL:         p.WebURL,
+		CreatedAt:       p.CreatedAt,
+		UpdatedAt:       p.LastActivityAt,
+		PushedAt:        p.LastActivityAt,
+		StargazersCount: p.StarCount,
+		ForksCount:      p.ForksCount,
+		OpenIssuesCount: p.OpenIssuesCount,
+		Topics:          topics,
+		Owner: model.Owner{
+			ID:    p.Namespace.ID,
+			Type:  p.Namespace.Kind,
+			Login: p.Namespace.FullPath,
+			URL:   p.Namespace.WebURL,
+		},
+	}
+}
@@ -0,0 +1,174 @@
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/MishraShardendu22/github-backup/model"
+)
+
+// fakeListing serves a page-numbered listing on one escaped path: pages[i] is the JSON array of page i+1,
+// and every later page is empty. Requests for other paths fail the test.
+func fakeListing(t *testing.T, path string, pages []string) (*httptest.Server, *[]string) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.URL.EscapedPath()+"?"+r.URL.RawQuery)
+		mu.Unlock()
+
+		if r.URL.EscapedPath() != path {
+			t.Errorf("unexpected request %s", r.URL.RequestURI())
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			t.Errorf("%s: Authorization = %q", r.URL.RequestURI(), r.Header.Get("Authorization"))
+		}
+
+		var page int
+		fmt.Sscan(r.URL.Query().Get("page"), &page)
+		if page >= 1 && page <= len(pages) {
+			w.Write([]byte(pages[page-1]))
+			return
+		}
+		w.Write([]byte("[]"))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func TestGitLabListRepos(t *testing.T) {
+	project := func(id int, path string) string {
+		return fmt.Sprintf(`{"id":%d,"name":"%s","path_with_namespace":"%s","visibility":"private",
+			"default_branch":"main","ssh_url_to_repo":"git@gitlab.example.com:%s.git","last_activity_at":"2026-10-01T10:00:00Z",
+			"tag_list":["go"],"namespace":{"id":9,"kind":"group","full_path":"acme/platform"}}`,
+			id, path[strings.LastIndex(path, "/")+1:], path, path)
+	}
+	pages := []string{
+		"[" + project(1, "acme/platform/api") + "," + project(2, "acme/platform/web") + "]",
+		"[" + project(3, "acme/platform/tools/cli") + "]",
+	}
+
+	tests := []struct {
+		name   string
+		source model.Source
+		path   string
+		query  string
+	}{
+		{name: "group", source: model.Source{Kind: model.SourceKindOrg, Account: "acme/platform"},
+			path: "/groups/acme%2Fplatform/projects", query: "include_subgroups=true&per_page=100"},
+		{name: "user", source: model.Source{Kind: model.SourceKindUser, Account: "octo"},
+			path: "/users/octo/projects", query: "per_page=100"},
+		{name: "authenticated user", source: model.Source{Kind: model.SourceKindAuthenticatedUser},
+			path: "/projects", query: "membership=true&per_page=100"},
+		{name: "starred by account", source: model.Source{Kind: model.SourceKindStarred, Account: "octo"},
+			path: "/users/octo/starred_projects", query: "per_page=100"},
+		{name: "starred by token owner", source: model.Source{Kind: model.SourceKindStarred},
+			path: "/projects", query: "starred=true&per_page=100"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, requests := fakeListing(t, tt.path, pages)
+			source := tt.source
+			source.Provider = model.ProviderGitLab
+			source.Token = "test-token"
+			source.APIURL = srv.URL
+
+			repos, err := GitLab{}.ListRepos(source)
+			if err != nil {
+				t.Fatalf("ListRepos: %v", err)
+			}
+
+			var names []string
+			for _, repo := range repos {
+				names = append(names, repo.FullName)
+			}
+			if strings.Join(names, " ") != "acme/platform/api acme/platform/web acme/platform/tools/cli" {
+				t.Errorf("repos = %v", names)
+			}
+			if len(*requests) != 3 {
+				t.Fatalf("requests = %v, want pages 1 to 3", *requests)
+			}
+			for i, request := range *requests {
+				if want := fmt.Sprintf("%s?%s&page=%d", tt.path, tt.query, i+1); request != want {
+					t.Errorf("request %d = %s, want %s", i, request, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGitLabProjectToRepo(t *testing.T) {
+	srv, _ := fakeListing(t, "/projects", []string{`[{"id":42,"name":"cli","path_with_namespace":"acme/tools/cli",
+		"description":"Command line","visibility":"internal","archived":true,"wiki_enabled":true,"default_branch":"trunk",
+		"ssh_url_to_repo":"git@gitlab.example.com:acme/tools/cli.git","http_url_to_repo":"https://gitlab.example.com/acme/tools/cli.git",
+		"web_url":"https://gitlab.example.com/acme/tools/cli","created_at":"2025-01-01T00:00:00Z","last_activity_at":"2026-10-01T10:00:00Z",
+		"star_count":5,"forks_count":2,"open_issues_count":7,"topics":[],"tag_list":["go","cli"],
+		"forked_from_project":{"id":41},"namespace":{"id":9,"kind":"group","full_path":"acme/tools","web_url":"https://gitlab.example.com/acme/tools"}}]`})
+
+	repos, err := GitLab{}.ListRepos(model.Source{Token: "test-token", APIURL: srv.URL})
+	if err != nil || len(repos) != 1 {
+		t.Fatalf("ListRepos = %v, %v", repos, err)
+	}
+	repo := repos[0]
+	if repo.ID != 42 || repo.Name != "cli" || repo.FullName != "acme/tools/cli" || repo.Visibility != "internal" ||
+		!repo.Private || !repo.Archived || !repo.Fork || repo.DefaultBranch != "trunk" || repo.PushedAt != "2026-10-01T10:00:00Z" ||
+		repo.StargazersCount != 5 || strings.Join(repo.Topics, ",") != "go,cli" {
+		t.Errorf("repo = %+v", repo)
+	}
+	if repo.Owner.Login != "acme/tools" || repo.Owner.Type != "group" || repo.Owner.ID != 9 {
+		t.Errorf("owner = %+v", repo.Owner)
+	}
+}
+
+func TestGitLabListReposError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "1" {
+			w.Write([]byte(`[{"id":1,"path_with_namespace":"acme/api"}]`))
+			return
+		}
+		http.Error(w, `{"message":"500 Internal Server Error"}`, http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	repos, err := GitLab{}.ListRepos(model.Source{Token: "test-token", APIURL: srv.URL})
+	if err == nil || !strings.Contains(err.Error(), "page 2") {
+		t.Fatalf("err = %v, want a failure on page 2", err)
+	}
+	if len(repos) != 1 || repos[0].FullName != "acme/api" {
+		t.Errorf("repos before the error = %+v", repos)
+	}
+}
+
+func TestGitLabCloneURL(t *testing.T) {
+	repo := model.Repo{FullName: "mirror/acme/api", SSHURL: "git@gitlab.example.com:acme/api.git"}
+
+	tests := []struct {
+		name   string
+		source model.Source
+		repo   model.Repo
+		want   string
+	}{
+		{name: "reported SSH URL", source: model.Source{}, repo: repo, want: "git@gitlab.example.com:acme/api.git"},
+		{name: "SSH host alias", source: model.Source{CloneHost: "git@gitlab-backup", Namespace: "mirror"}, repo: repo,
+			want: "git@gitlab-backup:acme/api.git"},
+		{name: "URL prefix", source: model.Source{CloneHost: "https://git.example.com/", Namespace: "mirror"}, repo: repo,
+			want: "https://git.example.com/acme/api.git"},
+		{name: "no SSH URL reported", source: model.Source{CloneHost: "file:///srv/git"}, repo: model.Repo{FullName: "acme/api"},
+			want: "file:///srv/git/acme/api.git"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (GitLab{}).CloneURL(tt.source, tt.repo); got != tt.want {
+				t.Errorf("CloneURL = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
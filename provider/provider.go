@@ -0,0 +1,82 @@
+package provider
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/MishraShardendu22/github-backup/controller"
+	"github.com/MishraShardendu22/github-backup/model"
+	"github.com/MishraShardendu22/github-backup/service/helper"
+)
+
+// Provider is a forge the worker can back up from. Every implementation maps its own API
+// onto model.Repo so the ProcessRepos pipeline does not care where a repository lives.
+type Provider interface {
+	// ListRepos returns every repository the source selects. On error the repos fetched so far are
+	// returned alongside it, so callers know the list is incomplete.
+	ListRepos(source model.Source) ([]model.Repo, error)
+	// CloneURL is the git remote the worker clones and ls-remotes
+	CloneURL(source model.Source, repo model.Repo) string
+	// FetchMetadata loads the current record of a single repository by its full name
+	FetchMetadata(source model.Source, fullName string) (model.Repo, error)
+}
+
+// For returns the provider implementation a source is configured with
+func For(source model.Source) (Provider, error) {
+	switch source.Provider {
+	case "", model.ProviderGitHub:
+		return GitHub{}, nil
+	case model.ProviderGitLab:
+		return GitLab{}, nil
+	case model.ProviderGitea:
+		return Gitea{}, nil
+	case model.ProviderGit:
+		return Remotes{}, nil
+	default:
+		return nil, fmt.Errorf("unknown provider %q", source.Provider)
+	}
+}
+
+// listPages walks a page-numbered listing until a page decodes to no entries
+func listPages(pageURL string, token string, decode func(body []byte) (int, error)) error {
+	client := controller.GitHubAPI()
+
+	for page := 1; ; page++ {
+		res, err := client.Get(pageURL+strconv.Itoa(page), token)
+		if err != nil {
+			return fmt.Errorf("fetch page %d: %w", page, err)
+		}
+		if res.StatusCode != 200 {
+			return fmt.Errorf("unexpected status %d on page %d: %s", res.StatusCode, page, string(res.Body))
+		}
+
+		count, err := decode(res.Body)
+		if err != nil {
+			return fmt.Errorf("decode page %d: %w", page, err)
+		}
+		if count == 0 {
+			return nil
+		}
+	}
+}
+
+// getJSON fetches a single API document
+func getJSON(url string, token string) ([]byte, error) {
+	res, err := controller.GitHubAPI().Get(url, token)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != 200 {
+		return nil, fmt.Errorf("unexpected status %d: %s", res.StatusCode, string(res.Body))
+	}
+
+	return res.Body, nil
+}
+
+// cloneURL prefers an explicitly configured clone host and falls back to the SSH URL the forge reported
+func cloneURL(source model.Source, repo model.Repo) string {
+	if source.CloneHost != "" || repo.SSHURL == "" {
+		return helper.BuildCloneURL(source.CloneHost, source.RemoteName(repo.FullName))
+	}
+	return repo.SSHURL
+}
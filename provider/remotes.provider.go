@@ -0,0 +1,48 @@
+package provider
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/MishraShardendu22/github-backup/model"
+)
+
+// Remotes serves the explicitly configured git URLs of a git provider source. There is no API to ask,
+// so the repo records are built from the configuration alone.
+type Remotes struct{}
+
+func (Remotes) ListRepos(source model.Source) ([]model.Repo, error) {
+	repos := make([]model.Repo, 0, len(source.Remotes))
+	for _, remote := range source.Remotes {
+		repos = append(repos, remoteRepo(remote))
+	}
+	return repos, nil
+}
+
+func (Remotes) CloneURL(source model.Source, repo model.Repo) string {
+	name := source.RemoteName(repo.FullName)
+	for _, remote := range source.Remotes {
+		if remote.Name == name {
+			return remote.URL
+		}
+	}
+	return repo.CloneURL
+}
+
+func (Remotes) FetchMetadata(source model.Source, fullName string) (model.Repo, error) {
+	for _, remote := range source.Remotes {
+		if remote.Name == fullName {
+			return remoteRepo(remote), nil
+		}
+	}
+	return model.Repo{}, fmt.Errorf("remote %s is not configured", fullName)
+}
+
+func remoteRepo(remote model.Remote) model.Repo {
+	return model.Repo{
+		Name:     path.Base(remote.Name),
+		FullName: remote.Name,
+		CloneURL: remote.URL,
+		Owner:    model.Owner{Login: path.Dir(remote.Name)},
+	}
+}
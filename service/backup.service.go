@@ -2,11 +2,16 @@ package service
 
 import (
 	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
 
-	"github.com/MishraShardendu22/github-backup/config"
 	"github.com/MishraShardendu22/github-backup/controller"
 	"github.com/MishraShardendu22/github-backup/database"
 	"github.com/MishraShardendu22/github-backup/model"
+	"github.com/MishraShardendu22/github-backup/provider"
+	"github.com/MishraShardendu22/github-backup/service/helper"
+	"github.com/MishraShardendu22/github-backup/service/monitor"
 	"github.com/MishraShardendu22/github-backup/util"
 	"go.uber.org/zap"
 )
@@ -26,55 +31,273 @@ func RunBackupFlow(cfg *model.ConfigModel, db *sql.DB) {
 		return
 	}
 
-	urls := config.ImportantURL(cfg)
-	allRepos := GetAllRepos(cfg, urls)
+	controller.InitGitHubClient(db)
 
+	if len(cfg.Sources) == 0 {
+		util.Logger().Warn("No discovery sources configured; set SOURCES_FILE or ORG_ACCOUNT/PROJECT_ACCOUNT/GITHUB_TOKEN_PRIVATE")
+		return
+	}
+
+	discoveries := GetAllRepos(cfg.Sources)
+
+	var allRepos []model.Repo
+	var excluded []excludedRepo
+	var failedSources []string
+	for _, sd := range discoveries {
+		allRepos = append(allRepos, sd.Repos...)
+		excluded = append(excluded, sd.Excluded...)
+		if sd.Err != nil {
+			failedSources = append(failedSources, sd.Source.Name)
+		}
+	}
+
+	allRepos, globallyExcluded, err := filterRepos(allRepos, cfg.Filters)
+	if err != nil {
+		util.ErrorHandler(err)
+		return
+	}
+	excluded = append(excluded, globallyExcluded...)
 	allRepos = deduplicateRepos(allRepos)
+	if cfg.BackupWikis {
+		allRepos = appendWikis(allRepos)
+	}
+	sourceCounts := countSources(discoveries, excluded)
 
-	util.Logger().Info("Repositories loaded (after dedup)",
+	util.Logger().Info("Repositories loaded (after filter and dedup)",
 		zap.Int("count", len(allRepos)),
+		zap.Int("excluded", len(excluded)),
+		zap.Strings("failed_sources", failedSources),
 	)
 
+	mon := monitor.Get()
 	if len(allRepos) == 0 {
+		reportExcludedRepos(excluded)
+		if len(failedSources) > 0 {
+			errMsg := fmt.Sprintf("Discovery failed for: %s", strings.Join(failedSources, ", "))
+			util.Logger().Error("No repositories discovered and some sources failed",
+				zap.Strings("failed_sources", failedSources),
+			)
+			if mon != nil {
+				mon.StartRun(0)
+				reportSourceCounts(sourceCounts)
+				mon.Log("error", errMsg, "")
+				mon.CompleteRun(0, 0, 0, 0, errMsg)
+			}
+			return
+		}
 		util.Logger().Warn("No repositories found; nothing to back up")
 		return
 	}
 
+	if mon != nil {
+		mon.StartRun(len(allRepos))
+		if len(failedSources) > 0 {
+			reason := fmt.Sprintf("Discovery incomplete; failed sources: %s", strings.Join(failedSources, ", "))
+			mon.MarkPartial(reason)
+			mon.Log("warn", reason+". Deleted-repo cleanup is skipped for this run.", "")
+		}
+	}
+	reportSourceCounts(sourceCounts)
+	reportExcludedRepos(excluded)
+
 	printRepoList(allRepos)
-	ProcessRepos(allRepos, cfg, db)
+
+	discovery := DiscoveryResult{Repos: allRepos, FailedSources: failedSources, Sources: sourceCounts}
+	for _, ex := range excluded {
+		discovery.Excluded = append(discovery.Excluded, ex.Repo)
+		// An excluded repo keeps its wiki's archive too, so the wiki must not look deleted upstream
+		if cfg.BackupWikis && ex.Repo.HasWiki && ex.Repo.WikiOf == "" {
+			discovery.Excluded = append(discovery.Excluded, wikiRepo(ex.Repo))
+		}
+	}
+	ProcessRepos(discovery, cfg, db)
 }
 
-func GetAllRepos(config *model.ConfigModel, urls *model.URL) []string {
-	orgReposPersonal := controller.RepoController(urls.GetAllOrgRepos, *config)
-	publicReposPersonal := controller.RepoController(urls.GetAllPublicRepos, *config)
-	privatePersonalAndOrgRepos := controller.RepoControllerPrivate(urls.GetAllPrivateRepos, *config)
+// DiscoveryResult is what a discovery pass found upstream
+type DiscoveryResult struct {
+	// Repos are the repositories selected for backup
+	Repos []model.Repo
+	// Excluded still exist upstream but were dropped by filter rules, so their archives are kept
+	Excluded []model.Repo
+	// FailedSources names the discovery sources that errored; the repo list is incomplete when set
+	FailedSources []string
+	// Sources holds the per-source counts reported in the run summary
+	Sources []model.SourceCount
+}
 
-	var allRepos []string
-	allRepos = append(allRepos, orgReposPersonal...)
-	util.Logger().Info("Org repositories loaded",
-		zap.Int("count", len(orgReposPersonal)),
-	)
+// Present lists every repository known to exist upstream, backed up or not
+func (d DiscoveryResult) Present() []model.Repo {
+	present := make([]model.Repo, 0, len(d.Repos)+len(d.Excluded))
+	present = append(present, d.Repos...)
+	return append(present, d.Excluded...)
+}
 
-	allRepos = append(allRepos, publicReposPersonal...)
-	util.Logger().Info("Public repositories loaded",
-		zap.Int("count", len(publicReposPersonal)),
-	)
+// Complete reports whether every source answered, which is required before treating
+// a missing repo as deleted upstream
+func (d DiscoveryResult) Complete() bool {
+	return len(d.FailedSources) == 0
+}
+
+// SourceDiscovery is what a single source returned. Repos fetched before a failure are kept in Repos
+// and Err is set, so the caller can back them up while treating the overall list as incomplete.
+type SourceDiscovery struct {
+	Source   model.Source
+	Repos    []model.Repo
+	Excluded []excludedRepo
+	Err      error
+}
+
+// GetAllRepos queries every source concurrently and keeps going when one fails.
+// Results are returned in the configured source order.
+func GetAllRepos(sources []model.Source) []SourceDiscovery {
+	discoveries := make([]SourceDiscovery, len(sources))
+	var wg sync.WaitGroup
+
+	for i, source := range sources {
+		wg.Add(1)
+		go func(idx int, source model.Source) {
+			defer wg.Done()
+			discoveries[idx] = discoverSource(source)
+		}(i, source)
+	}
+
+	wg.Wait()
+	return discoveries
+}
+
+func discoverSource(source model.Source) SourceDiscovery {
+	var repos []model.Repo
+	forge, err := provider.For(source)
+	if err == nil {
+		repos, err = forge.ListRepos(source)
+	}
+
+	for i := range repos {
+		repos[i].Source = source.Name
+		repos[i].Provider = source.Provider
+		if source.Namespace != "" {
+			repos[i].FullName = source.Namespace + "/" + repos[i].FullName
+		}
+	}
+
+	sd := SourceDiscovery{Source: source, Repos: repos, Err: err}
+	if source.Filters != nil {
+		var filterErr error
+		sd.Repos, sd.Excluded, filterErr = filterRepos(repos, *source.Filters)
+		if filterErr != nil && err == nil {
+			err = fmt.Errorf("source filters: %w", filterErr)
+			sd.Err = err
+		}
+	}
 
-	allRepos = append(allRepos, privatePersonalAndOrgRepos...)
-	util.Logger().Info("Private repositories loaded",
-		zap.Int("count", len(privatePersonalAndOrgRepos)),
+	if err != nil {
+		util.Logger().Error("Repository discovery failed for source",
+			zap.String("source", source.Name),
+			zap.String("provider", source.Provider),
+			zap.String("kind", source.Kind),
+			zap.Int("fetched_before_failure", len(repos)),
+			zap.Error(err),
+		)
+		return sd
+	}
+
+	util.Logger().Info("Repositories loaded",
+		zap.String("source", source.Name),
+		zap.String("provider", source.Provider),
+		zap.String("kind", source.Kind),
+		zap.String("account", source.Account),
+		zap.Int("count", len(repos)),
+		zap.Int("excluded_by_source_filters", len(sd.Excluded)),
 	)
 
-	return allRepos
+	return sd
+}
+
+// countSources attributes every discovered and excluded repo to the source that found it
+func countSources(discoveries []SourceDiscovery, excluded []excludedRepo) []model.SourceCount {
+	counts := make([]model.SourceCount, len(discoveries))
+	index := make(map[string]int, len(discoveries))
+	for i, sd := range discoveries {
+		counts[i] = model.SourceCount{
+			Name:       sd.Source.Name,
+			Kind:       sd.Source.Kind,
+			Discovered: len(sd.Repos) + len(sd.Excluded),
+			Failed:     sd.Err != nil,
+		}
+		index[sd.Source.Name] = i
+	}
+
+	for _, ex := range excluded {
+		if i, ok := index[ex.Repo.Source]; ok {
+			counts[i].Excluded++
+		}
+	}
+
+	return counts
+}
+
+func reportSourceCounts(counts []model.SourceCount) {
+	mon := monitor.Get()
+	for _, count := range counts {
+		status := "ok"
+		if count.Failed {
+			status = "failed"
+		}
+		util.Logger().Info("Source discovery",
+			zap.String("source", count.Name),
+			zap.String("kind", count.Kind),
+			zap.String("status", status),
+			zap.Int("discovered", count.Discovered),
+			zap.Int("excluded", count.Excluded),
+		)
+		if mon != nil {
+			mon.Log("info", fmt.Sprintf("Source %s (%s): %d discovered, %d excluded, %s",
+				count.Name, count.Kind, count.Discovered, count.Excluded, status), "")
+		}
+	}
+}
+
+// sourceFor returns the configured source that discovered the repo
+func sourceFor(cfg *model.ConfigModel, repo model.Repo) (model.Source, bool) {
+	for _, source := range cfg.Sources {
+		if source.Name == repo.Source {
+			return source, true
+		}
+	}
+	return model.Source{}, false
+}
+
+// cloneURLFor asks the provider of the source that discovered the repo for its clone URL
+func cloneURLFor(cfg *model.ConfigModel, repo model.Repo) string {
+	if source, ok := sourceFor(cfg, repo); ok {
+		if forge, err := provider.For(source); err == nil {
+			return forge.CloneURL(source, repo)
+		}
+	}
+	return helper.BuildCloneURL(cfg.GitCloneHost, repo.FullName)
 }
 
-func deduplicateRepos(repos []string) []string {
+// gitEnvFor returns the environment that authenticates git against the repo's source. Only GitHub App
+// sources need one; the installation token is fetched per call so long runs pick up refreshed tokens.
+func gitEnvFor(cfg *model.ConfigModel, repo model.Repo) ([]string, error) {
+	source, ok := sourceFor(cfg, repo)
+	if !ok || source.App == nil {
+		return nil, nil
+	}
+	token, err := controller.SourceToken(source)
+	if err != nil {
+		return nil, fmt.Errorf("installation token for source %s: %w", source.Name, err)
+	}
+	return helper.GitTokenEnv(token), nil
+}
+
+func deduplicateRepos(repos []model.Repo) []model.Repo {
 	seen := make(map[string]bool, len(repos))
-	unique := make([]string, 0, len(repos))
+	unique := make([]model.Repo, 0, len(repos))
 
 	for _, repo := range repos {
-		if !seen[repo] {
-			seen[repo] = true
+		if !seen[repo.FullName] {
+			seen[repo.FullName] = true
 			unique = append(unique, repo)
 		}
 	}
@@ -90,22 +313,53 @@ func deduplicateRepos(repos []string) []string {
 	return unique
 }
 
-func printRepoList(repos []string) {
+func printRepoList(repos []model.Repo) {
 	for _, repo := range repos {
 		util.Logger().Info("Repository discovered",
-			zap.String("repository", repo),
+			zap.String("repository", repo.FullName),
+			zap.String("visibility", repo.Visibility),
+			zap.Bool("fork", repo.Fork),
+			zap.Bool("archived", repo.Archived),
 		)
 	}
 }
 
-func printBackupSummary(repoNames []string, successCount int, skippedCount int, failedRepos []string) {
+func printBackupSummary(repos []model.Repo, sources []model.SourceCount, successCount int, skippedCount int, failedRepos []string) {
 	util.Logger().Info("Backup summary",
-		zap.Int("total", len(repoNames)),
+		zap.Int("total", len(repos)),
 		zap.Int("successful", successCount),
 		zap.Int("skipped_unchanged", skippedCount),
 		zap.Int("failed", len(failedRepos)),
 	)
 
+	failed := make(map[string]bool, len(failedRepos))
+	for _, repo := range failedRepos {
+		failed[repo] = true
+	}
+	selected := make(map[string]int, len(sources))
+	failedBySource := make(map[string]int, len(sources))
+	for _, repo := range repos {
+		selected[repo.Source]++
+		if failed[repo.FullName] {
+			failedBySource[repo.Source]++
+		}
+	}
+	mon := monitor.Get()
+	for _, source := range sources {
+		if mon != nil {
+			mon.Log("info", fmt.Sprintf("Source %s: %d discovered, %d excluded, %d selected, %d failed",
+				source.Name, source.Discovered, source.Excluded, selected[source.Name], failedBySource[source.Name]), "")
+		}
+		util.Logger().Info("Backup summary for source",
+			zap.String("source", source.Name),
+			zap.Int("discovered", source.Discovered),
+			zap.Int("excluded", source.Excluded),
+			zap.Int("selected", selected[source.Name]),
+			zap.Int("failed", failedBySource[source.Name]),
+			zap.Bool("discovery_failed", source.Failed),
+		)
+	}
+
 	if len(failedRepos) > 0 {
 		for _, repo := range failedRepos {
 			util.Logger().Warn("Repository backup failed",
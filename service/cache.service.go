@@ -0,0 +1,163 @@
+package service
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/MishraShardendu22/github-backup/model"
+	"github.com/MishraShardendu22/github-backup/service/helper"
+	"github.com/MishraShardendu22/github-backup/service/monitor"
+	"github.com/MishraShardendu22/github-backup/util"
+	"go.uber.org/zap"
+)
+
+// cachedMirror is one bare mirror in the cache; its directory's modification time is its last use
+type cachedMirror struct {
+	dir      string
+	size     int64
+	lastUsed time.Time
+}
+
+// syncMirrorCache returns the absolute path of the repo's bare mirror in the cache, fetching what changed
+// upstream into it, or cloning it when it is missing or cannot be fetched. Bundles, and the snapshots of
+// repos backed up in both modes, are made from this local mirror, so only new objects cross the network.
+func syncMirrorCache(config *model.ConfigModel, hr repoHashResult, gitEnv []string) (string, error) {
+	mirrorDir, err := filepath.Abs(filepath.Join(config.MirrorCacheDir, hr.RepoPath+".git"))
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(mirrorDir), 0o755); err != nil {
+		return "", err
+	}
+
+	fetched := false
+	if _, statErr := os.Stat(filepath.Join(mirrorDir, "HEAD")); statErr == nil {
+		if err := helper.FetchMirror(mirrorDir, hr.URL, gitEnv); err != nil {
+			util.Logger().Warn("Failed to fetch cached mirror; cloning it again",
+				zap.String("repository", hr.FullName),
+				zap.Error(err),
+			)
+		} else {
+			fetched = true
+		}
+	}
+	if !fetched {
+		if err := helper.CloneMirror(hr.URL, mirrorDir, gitEnv); err != nil {
+			return "", err
+		}
+	}
+
+	if hr.Repo.DefaultBranch != "" {
+		if err := helper.SetMirrorHead(mirrorDir, hr.Repo.DefaultBranch); err != nil {
+			return "", err
+		}
+	}
+
+	now := time.Now()
+	if err := os.Chtimes(mirrorDir, now, now); err != nil {
+		return "", err
+	}
+
+	util.Logger().Info("Mirror cache updated",
+		zap.String("repository", hr.FullName),
+		zap.Bool("fetched", fetched),
+	)
+	return mirrorDir, nil
+}
+
+// evictMirrorCache removes the least recently used mirrors until the cache fits MIRROR_CACHE_MAX_GB.
+// An evicted repo is simply cloned again the next time it changes.
+func evictMirrorCache(config *model.ConfigModel) {
+	if config.MirrorCacheMaxGB <= 0 {
+		return
+	}
+	budget := int64(config.MirrorCacheMaxGB) * 1024 * 1024 * 1024
+
+	mirrors, err := listCachedMirrors(config.MirrorCacheDir)
+	if err != nil {
+		util.Logger().Warn("Failed to read mirror cache", zap.Error(err))
+		return
+	}
+
+	var total int64
+	for _, mirror := range mirrors {
+		total += mirror.size
+	}
+	if total <= budget {
+		return
+	}
+
+	sort.Slice(mirrors, func(i, j int) bool { return mirrors[i].lastUsed.Before(mirrors[j].lastUsed) })
+
+	mon := monitor.Get()
+	evicted := 0
+	for _, mirror := range mirrors {
+		if total <= budget {
+			break
+		}
+		if err := os.RemoveAll(mirror.dir); err != nil {
+			util.Logger().Warn("Failed to evict cached mirror",
+				zap.String("mirror", mirror.dir),
+				zap.Error(err),
+			)
+			continue
+		}
+		total -= mirror.size
+		evicted++
+	}
+
+	util.Logger().Info("Mirror cache evicted",
+		zap.Int("evicted", evicted),
+		zap.Int64("size_mb", total/(1024*1024)),
+		zap.Int("budget_gb", config.MirrorCacheMaxGB),
+	)
+	if mon != nil {
+		mon.Log("info", fmt.Sprintf("Mirror cache: evicted %d least recently used mirror(s) to stay under %d GB",
+			evicted, config.MirrorCacheMaxGB), "")
+	}
+}
+
+// listCachedMirrors finds every <repo>.git bare mirror below the cache directory with its size on disk
+func listCachedMirrors(cacheDir string) ([]cachedMirror, error) {
+	var mirrors []cachedMirror
+	err := filepath.WalkDir(cacheDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == cacheDir {
+				return filepath.SkipAll
+			}
+			return err
+		}
+		if !entry.IsDir() || !strings.HasSuffix(entry.Name(), ".git") {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		mirror := cachedMirror{dir: path, lastUsed: info.ModTime()}
+		if err := filepath.WalkDir(path, func(_ string, file fs.DirEntry, err error) error {
+			if err != nil || file.IsDir() {
+				return err
+			}
+			fileInfo, err := file.Info()
+			if err != nil {
+				return err
+			}
+			mirror.size += fileInfo.Size()
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		mirrors = append(mirrors, mirror)
+		return filepath.SkipDir
+	})
+
+	return mirrors, err
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MishraShardendu22/github-backup/model"
+)
+
+const mib = 1024 * 1024
+
+// cacheMirror creates a bare mirror in the cache holding a sparse pack of sizeMiB, last used age ago
+func cacheMirror(t *testing.T, cacheDir string, repoPath string, sizeMiB int64, age time.Duration) {
+	t.Helper()
+
+	dir := filepath.Join(cacheDir, repoPath+".git")
+	pack := filepath.Join(dir, "objects", "pack", "pack-1.pack")
+	if err := os.MkdirAll(filepath.Dir(pack), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "HEAD"), []byte("ref: refs/heads/main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Create(pack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	// HEAD adds its 21 bytes on top
+	if err := file.Truncate(sizeMiB*mib - 21); err != nil {
+		t.Fatal(err)
+	}
+
+	lastUsed := time.Now().Add(-age)
+	if err := os.Chtimes(dir, lastUsed, lastUsed); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func cachedRepoPaths(t *testing.T, cacheDir string) []string {
+	t.Helper()
+
+	mirrors, err := listCachedMirrors(cacheDir)
+	if err != nil {
+		t.Fatalf("listCachedMirrors: %v", err)
+	}
+	var paths []string
+	for _, mirror := range mirrors {
+		rel, _ := filepath.Rel(cacheDir, mirror.dir)
+		paths = append(paths, strings.TrimSuffix(rel, ".git"))
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func TestListCachedMirrors(t *testing.T) {
+	cacheDir := t.TempDir()
+	cacheMirror(t, cacheDir, "acme/api", 3, time.Hour)
+	cacheMirror(t, cacheDir, "group/sub/tool", 1, 2*time.Hour)
+	if err := os.MkdirAll(filepath.Join(cacheDir, "acme", "not-a-mirror"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	mirrors, err := listCachedMirrors(cacheDir)
+	if err != nil {
+		t.Fatalf("listCachedMirrors: %v", err)
+	}
+	sizes := make(map[string]int64)
+	for _, mirror := range mirrors {
+		rel, _ := filepath.Rel(cacheDir, mirror.dir)
+		sizes[rel] = mirror.size
+		if time.Since(mirror.lastUsed) < time.Hour-time.Minute {
+			t.Errorf("%s: last used %v, want the directory's modification time", rel, mirror.lastUsed)
+		}
+	}
+	if len(sizes) != 2 || sizes["acme/api.git"] != 3*mib || sizes["group/sub/tool.git"] != 1*mib {
+		t.Errorf("mirrors = %v", sizes)
+	}
+
+	if mirrors, err := listCachedMirrors(filepath.Join(cacheDir, "missing")); err != nil || len(mirrors) != 0 {
+		t.Errorf("missing cache dir = %v, %v", mirrors, err)
+	}
+}
+
+func TestEvictMirrorCache(t *testing.T) {
+	tests := []struct {
+		name     string
+		budgetGB int
+		mirrors  map[string]int64
+		want     []string
+	}{
+		{
+			name:     "under budget",
+			budgetGB: 1,
+			mirrors:  map[string]int64{"acme/a": 300, "acme/b": 300, "acme/c": 300},
+			want:     []string{"acme/a", "acme/b", "acme/c"},
+		},
+		{
+			name:     "exactly at budget",
+			budgetGB: 1,
+			mirrors:  map[string]int64{"acme/a": 512, "acme/b": 512},
+			want:     []string{"acme/a", "acme/b"},
+		},
+		{
+			name:     "least recently used first",
+			budgetGB: 1,
+			// a is the oldest; removing it alone leaves 1500 MiB, so b goes too
+			mirrors: map[string]int64{"acme/a": 100, "acme/b": 500, "acme/c": 400, "acme/d": 600},
+			want:    []string{"acme/c", "acme/d"},
+		},
+		{
+			name:     "stops once under budget",
+			budgetGB: 2,
+			mirrors:  map[string]int64{"acme/a": 1024, "acme/b": 700, "acme/c": 700},
+			want:     []string{"acme/b", "acme/c"},
+		},
+		{
+			name:     "disabled",
+			budgetGB: 0,
+			mirrors:  map[string]int64{"acme/a": 4096},
+			want:     []string{"acme/a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cacheDir := t.TempDir()
+			// Mirrors are used in name order: acme/a longest ago
+			names := make([]string, 0, len(tt.mirrors))
+			for name := range tt.mirrors {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for i, name := range names {
+				cacheMirror(t, cacheDir, name, tt.mirrors[name], time.Duration(len(names)-i)*time.Hour)
+			}
+
+			evictMirrorCache(&model.ConfigModel{MirrorCacheDir: cacheDir, MirrorCacheMaxGB: tt.budgetGB})
+
+			if got := cachedRepoPaths(t, cacheDir); strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("cached after eviction = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCloneUsesMirrorCacheByMode(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	upstream := filepath.Join(dir, "upstream")
+	runGit(t, dir, "init", "-q", upstream)
+	runGit(t, upstream, "checkout", "-q", "-b", "main")
+	commitFile(t, upstream, "a.txt")
+	if err := os.Mkdir("_Repos", 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &model.ConfigModel{MirrorCacheDir: "_MirrorCache", MirrorIncrementals: 5, MirrorFullDays: 30}
+	var repos []repoHashResult
+	for fullName, mode := range map[string]string{"acme/snapshot": model.BackupModeSnapshot, "acme/both": model.BackupModeBoth} {
+		repos = append(repos, repoHashResult{
+			Repo:     model.Repo{FullName: fullName, DefaultBranch: "main"},
+			FullName: fullName,
+			RepoPath: fullName,
+			URL:      upstream,
+			Mode:     mode,
+		})
+	}
+
+	for _, res := range parallelCloneAndArchive(repos, config, nil) {
+		if res.Err != nil {
+			t.Fatalf("%s: %v", res.FullName, res.Err)
+		}
+		if !backupArtifactsMatch(res.RepoPath, res.Mode) {
+			t.Errorf("%s: artifacts for mode %s missing from _Repos", res.FullName, res.Mode)
+		}
+	}
+	if got := cachedRepoPaths(t, config.MirrorCacheDir); strings.Join(got, " ") != "acme/both" {
+		t.Errorf("cached mirrors = %v, want only the repo backed up in both modes", got)
+	}
+}
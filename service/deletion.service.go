@@ -0,0 +1,393 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/MishraShardendu22/github-backup/database"
+	"github.com/MishraShardendu22/github-backup/model"
+	"github.com/MishraShardendu22/github-backup/service/helper"
+	"github.com/MishraShardendu22/github-backup/service/monitor"
+	"github.com/MishraShardendu22/github-backup/util"
+	"go.uber.org/zap"
+)
+
+// processDeletedRepos tombstones repos that exist in DB but are no longer on GitHub.
+// Deletion sets above the configured threshold are refused and parked for confirm-deletions.
+func processDeletedRepos(currentRepos []model.Repo, config *model.ConfigModel, db *sql.DB) {
+	if db == nil {
+		return
+	}
+
+	dbRepos, err := database.GetAllReposFromDB(db)
+	if err != nil {
+		util.Logger().Warn("Failed to fetch repos from DB for cleanup", zap.Error(err))
+		return
+	}
+
+	if len(dbRepos) == 0 {
+		return
+	}
+
+	// Build set of current repo names for O(1) lookup
+	currentSet := make(map[string]bool, len(currentRepos))
+	for _, repo := range currentRepos {
+		currentSet[repo.FullName] = true
+	}
+
+	// Find repos to delete
+	var toDelete []model.RepoRecord
+	for _, dbRepo := range dbRepos {
+		if !currentSet[dbRepo.FullName] {
+			toDelete = append(toDelete, dbRepo)
+		}
+	}
+
+	if len(toDelete) == 0 {
+		if err := database.ClearPendingDeletions(db); err != nil {
+			util.Logger().Warn("Failed to clear pending deletions", zap.Error(err))
+		}
+		return
+	}
+
+	if reason := deletionThresholdExceeded(len(toDelete), len(dbRepos), config); reason != "" {
+		refuseDeletions(toDelete, reason, db)
+		return
+	}
+
+	if err := database.ClearPendingDeletions(db); err != nil {
+		util.Logger().Warn("Failed to clear pending deletions", zap.Error(err))
+	}
+
+	tombstoneRepos(toDelete, db)
+}
+
+// deletionThresholdExceeded returns a reason when the deletion set is larger than the configured
+// absolute or percentage limits; a limit of 0 disables that check
+func deletionThresholdExceeded(count int, tracked int, config *model.ConfigModel) string {
+	if config.DeletionMaxCount > 0 && count > config.DeletionMaxCount {
+		return fmt.Sprintf("%d repos missing upstream exceeds DELETION_MAX_COUNT=%d", count, config.DeletionMaxCount)
+	}
+
+	if config.DeletionMaxPercent > 0 && tracked > 0 {
+		percent := float64(count) * 100 / float64(tracked)
+		if percent > config.DeletionMaxPercent {
+			return fmt.Sprintf("%d of %d tracked repos (%.1f%%) missing upstream exceeds DELETION_MAX_PERCENT=%.1f",
+				count, tracked, percent, config.DeletionMaxPercent)
+		}
+	}
+
+	return ""
+}
+
+func refuseDeletions(toDelete []model.RepoRecord, reason string, db *sql.DB) {
+	names := make([]string, 0, len(toDelete))
+	for _, repo := range toDelete {
+		names = append(names, repo.FullName)
+	}
+
+	if err := database.ReplacePendingDeletions(db, names, reason); err != nil {
+		util.Logger().Warn("Failed to store pending deletions", zap.Error(err))
+	}
+
+	util.Logger().Error("Refusing mass deletion of repositories; run `confirm-deletions` to apply it",
+		zap.String("reason", reason),
+		zap.Strings("repositories", names),
+	)
+
+	if mon := monitor.Get(); mon != nil {
+		mon.Log("error", fmt.Sprintf("ALERT: refused to delete %d repositories (%s). Run the worker with confirm-deletions to apply.",
+			len(names), reason), "")
+		for _, name := range names {
+			mon.Log("warn", "Deletion pending confirmation", name)
+		}
+	}
+}
+
+// ConfirmPendingDeletions applies the deletion set last refused by the safeguard
+func ConfirmPendingDeletions(config *model.ConfigModel, db *sql.DB) error {
+	if err := database.InitSchema(db); err != nil {
+		return err
+	}
+
+	pending, err := database.GetPendingDeletions(db)
+	if err != nil {
+		return err
+	}
+
+	if len(pending) == 0 {
+		util.Logger().Info("No pending deletions to confirm")
+		return nil
+	}
+
+	if err := helper.EnsureReposDirExists(); err != nil {
+		return err
+	}
+
+	if err := helper.EnsureBackupRepoInitialized(config); err != nil {
+		return err
+	}
+
+	var toDelete []model.RepoRecord
+	for _, fullName := range pending {
+		repo, found, err := database.GetRepo(db, fullName)
+		if err != nil {
+			return err
+		}
+		if !found {
+			util.Logger().Info("Pending deletion no longer tracked; skipping",
+				zap.String("repository", fullName),
+			)
+			continue
+		}
+		toDelete = append(toDelete, repo)
+	}
+
+	util.Logger().Info("Applying confirmed deletions",
+		zap.Int("count", len(toDelete)),
+	)
+
+	tombstoneRepos(toDelete, db)
+
+	return database.ClearPendingDeletions(db)
+}
+
+// tombstoneRepos moves archives of repos missing upstream under _Repos/_deleted and replaces their
+// repos rows with tombstones; nothing is removed until purgeExpiredTombstones runs after the retention period
+func tombstoneRepos(toDelete []model.RepoRecord, db *sql.DB) {
+	if len(toDelete) == 0 {
+		return
+	}
+
+	util.Logger().Info("Tombstoning repositories missing upstream",
+		zap.Int("count", len(toDelete)),
+	)
+
+	mon := monitor.Get()
+	tombstoned := 0
+
+	// Serial: git mv must not run concurrently inside _Repos
+	for _, repo := range toDelete {
+		repoPath := helper.RepoPath(repo.FullName)
+		archive := helper.ArchiveFileName(repoPath)
+
+		util.Logger().Info("Repository no longer on GitHub; moving archive to "+helper.DeletedPrefix,
+			zap.String("repository", repo.FullName),
+		)
+
+		if _, err := helper.MoveTrackedFile(archive, helper.DeletedPath(archive)); err != nil {
+			util.Logger().Warn("Failed to move deleted repo archive",
+				zap.String("repository", repo.FullName),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		for _, sidecar := range helper.ArchiveSidecars(repoPath) {
+			if _, err := helper.MoveTrackedFile(sidecar, helper.DeletedPath(sidecar)); err != nil {
+				util.Logger().Warn("Failed to move deleted repo metadata",
+					zap.String("repository", repo.FullName),
+					zap.String("file", sidecar),
+					zap.Error(err),
+				)
+			}
+		}
+
+		if err := database.TombstoneRepo(db, repo, helper.DeletedPath(archive)); err != nil {
+			util.Logger().Warn("Failed to tombstone repo in DB",
+				zap.String("repository", repo.FullName),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		tombstoned++
+		if mon != nil {
+			mon.Log("warn", "Repository missing upstream; archive moved to "+helper.DeletedPrefix, repo.FullName)
+		}
+	}
+
+	commitMsg := helper.SanitizeCommitMessage(fmt.Sprintf("Tombstoned %d deleted repo(s) on %s",
+		tombstoned, time.Now().Format("2006-01-02 Monday 15:04:05")))
+	pushIfCommitted(commitMsg, "deleted-repos-tombstone")
+}
+
+// restoreTombstonedRepos brings archives back from _deleted when their repo reappears upstream,
+// restoring the recorded hash so an unchanged repo is not cloned again
+func restoreTombstonedRepos(currentRepos []model.Repo, db *sql.DB) {
+	if db == nil {
+		return
+	}
+
+	tombstones, err := database.GetAllTombstones(db)
+	if err != nil {
+		util.Logger().Warn("Failed to fetch tombstones", zap.Error(err))
+		return
+	}
+
+	if len(tombstones) == 0 {
+		return
+	}
+
+	current := make(map[string]model.Repo, len(currentRepos))
+	for _, repo := range currentRepos {
+		current[repo.FullName] = repo
+	}
+
+	mon := monitor.Get()
+	restored := 0
+	for _, tombstone := range tombstones {
+		repo, ok := current[tombstone.FullName]
+		if !ok {
+			continue
+		}
+
+		repoPath := helper.RepoPath(tombstone.FullName)
+		archive := helper.ArchiveFileName(repoPath)
+
+		if _, err := helper.MoveTrackedFile(tombstone.ArchivePath, archive); err != nil {
+			util.Logger().Warn("Failed to restore tombstoned archive",
+				zap.String("repository", tombstone.FullName),
+				zap.Error(err),
+			)
+			continue
+		}
+		sidecars := helper.ArchiveSidecars(repoPath)
+		for i, sidecar := range helper.SidecarsForArchive(tombstone.ArchivePath) {
+			if _, err := helper.MoveTrackedFile(sidecar, sidecars[i]); err != nil {
+				util.Logger().Warn("Failed to restore tombstoned metadata",
+					zap.String("repository", tombstone.FullName),
+					zap.String("file", sidecar),
+					zap.Error(err),
+				)
+			}
+		}
+
+		if err := database.UpsertRepo(db, repo, tombstone.Name, tombstone.CloneURL, tombstone.LatestCommitHash); err != nil {
+			util.Logger().Warn("Failed to restore repo row",
+				zap.String("repository", tombstone.FullName),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		if err := database.DeleteTombstone(db, tombstone.FullName); err != nil {
+			util.Logger().Warn("Failed to delete tombstone",
+				zap.String("repository", tombstone.FullName),
+				zap.Error(err),
+			)
+		}
+
+		restored++
+		util.Logger().Info("Repository reappeared upstream; archive restored",
+			zap.String("repository", tombstone.FullName),
+			zap.Time("first_missing_at", tombstone.FirstMissingAt),
+		)
+		if mon != nil {
+			mon.Log("info", "Repository reappeared upstream; archive restored from "+helper.DeletedPrefix, tombstone.FullName)
+		}
+	}
+
+	if restored == 0 {
+		return
+	}
+
+	commitMsg := helper.SanitizeCommitMessage(fmt.Sprintf("Restored %d reappeared repo(s) on %s",
+		restored, time.Now().Format("2006-01-02 Monday 15:04:05")))
+	pushIfCommitted(commitMsg, "tombstone-restore")
+}
+
+// purgeExpiredTombstones removes archives from _deleted once they outlive the retention period
+func purgeExpiredTombstones(config *model.ConfigModel, db *sql.DB) {
+	if db == nil {
+		return
+	}
+
+	expired, err := database.GetExpiredTombstones(db, config.DeletedRetentionDays)
+	if err != nil {
+		util.Logger().Warn("Failed to fetch expired tombstones", zap.Error(err))
+		return
+	}
+
+	if len(expired) == 0 {
+		return
+	}
+
+	mon := monitor.Get()
+	purged := 0
+	for _, tombstone := range expired {
+		paths := append([]string{tombstone.ArchivePath}, helper.SidecarsForArchive(tombstone.ArchivePath)...)
+
+		if err := helper.RemoveTrackedFiles(paths...); err != nil {
+			util.Logger().Warn("Failed to purge tombstoned archive",
+				zap.String("repository", tombstone.FullName),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		if err := database.DeleteTombstone(db, tombstone.FullName); err != nil {
+			util.Logger().Warn("Failed to delete tombstone",
+				zap.String("repository", tombstone.FullName),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		if err := database.DeleteExportCursor(db, tombstone.FullName); err != nil {
+			util.Logger().Warn("Failed to delete export cursor",
+				zap.String("repository", tombstone.FullName),
+				zap.Error(err),
+			)
+		}
+		if err := database.DeleteRepoReleaseAssets(db, tombstone.FullName); err != nil {
+			util.Logger().Warn("Failed to delete release assets",
+				zap.String("repository", tombstone.FullName),
+				zap.Error(err),
+			)
+		}
+		if err := database.DeleteMirrorState(db, tombstone.FullName); err != nil {
+			util.Logger().Warn("Failed to delete mirror state",
+				zap.String("repository", tombstone.FullName),
+				zap.Error(err),
+			)
+		}
+		if err := database.DeleteRepoRefs(db, tombstone.FullName); err != nil {
+			util.Logger().Warn("Failed to delete repo refs",
+				zap.String("repository", tombstone.FullName),
+				zap.Error(err),
+			)
+		}
+
+		purged++
+		util.Logger().Info("Purged tombstoned repository after retention period",
+			zap.String("repository", tombstone.FullName),
+			zap.Time("first_missing_at", tombstone.FirstMissingAt),
+			zap.Int("retention_days", config.DeletedRetentionDays),
+		)
+		if mon != nil {
+			mon.Log("info", fmt.Sprintf("Purged archive after %d day retention", config.DeletedRetentionDays), tombstone.FullName)
+		}
+	}
+
+	commitMsg := helper.SanitizeCommitMessage(fmt.Sprintf("Purged %d deleted repo(s) past retention on %s",
+		purged, time.Now().Format("2006-01-02 Monday 15:04:05")))
+	pushIfCommitted(commitMsg, "tombstone-purge")
+}
+
+func pushIfCommitted(commitMsg string, label string) {
+	committed, err := helper.CommitStaged(commitMsg)
+	if err != nil {
+		util.Logger().Warn("Failed to commit", zap.String("label", label), zap.Error(err))
+		return
+	}
+
+	if !committed {
+		return
+	}
+
+	if err := helper.PushBackupRepo(label); err != nil {
+		util.Logger().Warn("Failed to push", zap.String("label", label), zap.Error(err))
+	}
+}
@@ -0,0 +1,281 @@
+package service
+
+import (
+	"database/sql"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MishraShardendu22/github-backup/database"
+	"github.com/MishraShardendu22/github-backup/model"
+	"github.com/MishraShardendu22/github-backup/service/helper"
+)
+
+// runGit runs git in dir and returns its trimmed output
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// newBackupFixture runs the test in a temporary directory holding an initialized _Repos that pushes to a
+// local bare origin, and returns the SQLite database the worker would use there
+func newBackupFixture(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dir := t.TempDir()
+	t.Chdir(dir)
+	for _, key := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(key, "Backup Test")
+	}
+	for _, key := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(key, "backup@example.com")
+	}
+
+	runGit(t, dir, "init", "-q", "--bare", "origin.git")
+	runGit(t, dir, "init", "-q", "_Repos")
+	runGit(t, "_Repos", "checkout", "-q", "-B", "main")
+	runGit(t, "_Repos", "remote", "add", "origin", filepath.Join(dir, "origin.git"))
+	runGit(t, "_Repos", "commit", "-q", "--allow-empty", "-m", "init")
+
+	db, err := database.ConnectSQLite(&model.ConfigModel{DBPath: filepath.Join(dir, "app.db")})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := database.InitSchema(db); err != nil {
+		t.Fatalf("init schema: %v", err)
+	}
+	return db
+}
+
+// archivePaths is where a repo's archive and metadata file live inside _Repos
+func archivePaths(fullName string) (string, string) {
+	repoPath := helper.RepoPath(fullName)
+	return helper.ArchiveFileName(repoPath), helper.MetadataFileName(repoPath)
+}
+
+// trackRepos records repos as backed up, with a committed archive and metadata file for each
+func trackRepos(t *testing.T, db *sql.DB, fullNames ...string) {
+	t.Helper()
+
+	for i, fullName := range fullNames {
+		archive, metadata := archivePaths(fullName)
+		for _, file := range []string{archive, metadata} {
+			path := filepath.Join("_Repos", file)
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(fullName), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		runGit(t, "_Repos", "add", archive, metadata)
+
+		repo := model.Repo{ID: 100 + i, Name: helper.ExtractRepoName(fullName), FullName: fullName}
+		if err := database.UpsertRepo(db, repo, repo.Name, "git@github.com:"+fullName+".git", "hash-"+fullName); err != nil {
+			t.Fatalf("upsert %s: %v", fullName, err)
+		}
+	}
+	runGit(t, "_Repos", "commit", "-q", "-m", "backup")
+}
+
+// discovered is a discovery result listing fullNames
+func discovered(fullNames ...string) []model.Repo {
+	repos := make([]model.Repo, 0, len(fullNames))
+	for _, fullName := range fullNames {
+		repos = append(repos, model.Repo{Name: helper.ExtractRepoName(fullName), FullName: fullName})
+	}
+	return repos
+}
+
+// trackedNames lists the full names in the repos table
+func trackedNames(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+
+	repos, err := database.GetAllReposFromDB(db)
+	if err != nil {
+		t.Fatalf("list repos: %v", err)
+	}
+	names := make([]string, 0, len(repos))
+	for _, repo := range repos {
+		names = append(names, repo.FullName)
+	}
+	return names
+}
+
+// inRepos reports whether file exists in the _Repos working tree
+func inRepos(file string) bool {
+	_, err := os.Stat(filepath.Join("_Repos", file))
+	return err == nil
+}
+
+func TestDeletionThresholdExceeded(t *testing.T) {
+	tests := []struct {
+		name       string
+		count      int
+		tracked    int
+		maxCount   int
+		maxPercent float64
+		refused    bool
+	}{
+		{name: "no limits", count: 90, tracked: 100},
+		{name: "at count limit", count: 5, tracked: 100, maxCount: 5},
+		{name: "over count limit", count: 6, tracked: 100, maxCount: 5, refused: true},
+		{name: "at percent limit", count: 10, tracked: 100, maxPercent: 10},
+		{name: "over percent limit", count: 11, tracked: 100, maxPercent: 10, refused: true},
+		{name: "percent of a small set", count: 1, tracked: 3, maxPercent: 25, refused: true},
+		{name: "count limit hit first", count: 6, tracked: 1000, maxCount: 5, maxPercent: 50, refused: true},
+		{name: "nothing tracked", count: 0, tracked: 0, maxPercent: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &model.ConfigModel{DeletionMaxCount: tt.maxCount, DeletionMaxPercent: tt.maxPercent}
+			if reason := deletionThresholdExceeded(tt.count, tt.tracked, config); (reason != "") != tt.refused {
+				t.Errorf("reason = %q, want refused %v", reason, tt.refused)
+			}
+		})
+	}
+}
+
+func TestConfirmPendingDeletions(t *testing.T) {
+	db := newBackupFixture(t)
+	trackRepos(t, db, "acme/api", "acme/web", "acme/docs", "acme/tools")
+	config := &model.ConfigModel{DeletionMaxCount: 2}
+
+	processDeletedRepos(discovered("acme/api"), config, db)
+
+	pending, err := database.GetPendingDeletions(db)
+	if err != nil {
+		t.Fatalf("pending deletions: %v", err)
+	}
+	if strings.Join(pending, " ") != "acme/docs acme/tools acme/web" {
+		t.Fatalf("pending = %v", pending)
+	}
+	if names := trackedNames(t, db); len(names) != 4 {
+		t.Fatalf("refused deletion still changed tracked repos: %v", names)
+	}
+
+	if err := ConfirmPendingDeletions(config, db); err != nil {
+		t.Fatalf("ConfirmPendingDeletions: %v", err)
+	}
+
+	if names := trackedNames(t, db); strings.Join(names, " ") != "acme/api" {
+		t.Errorf("tracked after confirm = %v", names)
+	}
+	if pending, _ := database.GetPendingDeletions(db); len(pending) != 0 {
+		t.Errorf("pending after confirm = %v", pending)
+	}
+	for _, fullName := range []string{"acme/web", "acme/docs", "acme/tools"} {
+		archive, metadata := archivePaths(fullName)
+		if inRepos(archive) || inRepos(metadata) {
+			t.Errorf("%s: archive still in place", fullName)
+		}
+		if !inRepos(helper.DeletedPath(archive)) || !inRepos(helper.DeletedPath(metadata)) {
+			t.Errorf("%s: archive not moved to %s", fullName, helper.DeletedPrefix)
+		}
+	}
+
+	tombstones, err := database.GetAllTombstones(db)
+	if err != nil || len(tombstones) != 3 {
+		t.Fatalf("tombstones = %+v, %v", tombstones, err)
+	}
+	for _, tombstone := range tombstones {
+		archive, _ := archivePaths(tombstone.FullName)
+		if tombstone.ArchivePath != helper.DeletedPath(archive) || tombstone.LatestCommitHash != "hash-"+tombstone.FullName {
+			t.Errorf("tombstone = %+v", tombstone)
+		}
+	}
+
+	if subject := runGit(t, ".", "--git-dir", "origin.git", "log", "-1", "--format=%s", "main"); !strings.HasPrefix(subject, "Tombstoned 3 deleted repo(s)") {
+		t.Errorf("pushed commit = %q", subject)
+	}
+}
+
+func TestConfirmPendingDeletionsSkipsUntrackedRepos(t *testing.T) {
+	db := newBackupFixture(t)
+	trackRepos(t, db, "acme/api", "acme/web")
+
+	if err := database.ReplacePendingDeletions(db, []string{"acme/web", "acme/gone"}, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ConfirmPendingDeletions(&model.ConfigModel{}, db); err != nil {
+		t.Fatalf("ConfirmPendingDeletions: %v", err)
+	}
+
+	if names := trackedNames(t, db); strings.Join(names, " ") != "acme/api" {
+		t.Errorf("tracked after confirm = %v", names)
+	}
+	if pending, _ := database.GetPendingDeletions(db); len(pending) != 0 {
+		t.Errorf("pending after confirm = %v", pending)
+	}
+}
+
+func TestRestoreTombstonedRepos(t *testing.T) {
+	db := newBackupFixture(t)
+	trackRepos(t, db, "acme/api", "acme/web")
+	config := &model.ConfigModel{}
+
+	processDeletedRepos(discovered("acme/api"), config, db)
+	if names := trackedNames(t, db); strings.Join(names, " ") != "acme/api" {
+		t.Fatalf("tracked after deletion = %v", names)
+	}
+
+	restoreTombstonedRepos(discovered("acme/api", "acme/web"), db)
+
+	archive, metadata := archivePaths("acme/web")
+	if !inRepos(archive) || !inRepos(metadata) {
+		t.Error("archive not restored")
+	}
+	if inRepos(helper.DeletedPath(archive)) {
+		t.Error("tombstoned archive left behind")
+	}
+	repo, found, err := database.GetRepo(db, "acme/web")
+	if err != nil || !found || repo.LatestCommitHash != "hash-acme/web" {
+		t.Errorf("restored repo = %+v, %v, %v", repo, found, err)
+	}
+	if tombstones, _ := database.GetAllTombstones(db); len(tombstones) != 0 {
+		t.Errorf("tombstones after restore = %+v", tombstones)
+	}
+	if status := runGit(t, "_Repos", "status", "--porcelain"); status != "" {
+		t.Errorf("restore left uncommitted changes: %s", status)
+	}
+}
+
+func TestPurgeExpiredTombstones(t *testing.T) {
+	db := newBackupFixture(t)
+	trackRepos(t, db, "acme/api", "acme/web", "acme/docs")
+	config := &model.ConfigModel{DeletedRetentionDays: 30}
+
+	processDeletedRepos(discovered("acme/api"), config, db)
+	if _, err := db.Exec(`UPDATE tombstones SET first_missing_at = datetime('now', '-31 days') WHERE full_name = ?`, "acme/web"); err != nil {
+		t.Fatal(err)
+	}
+
+	purgeExpiredTombstones(config, db)
+
+	tombstones, err := database.GetAllTombstones(db)
+	if err != nil || len(tombstones) != 1 || tombstones[0].FullName != "acme/docs" {
+		t.Fatalf("tombstones after purge = %+v, %v", tombstones, err)
+	}
+
+	expired, _ := archivePaths("acme/web")
+	if inRepos(helper.DeletedPath(expired)) {
+		t.Error("expired archive not purged")
+	}
+	if files := runGit(t, "_Repos", "ls-files", helper.DeletedPath(expired)); files != "" {
+		t.Errorf("expired archive still tracked: %s", files)
+	}
+	kept, _ := archivePaths("acme/docs")
+	if !inRepos(helper.DeletedPath(kept)) {
+		t.Error("archive within retention purged")
+	}
+}
@@ -0,0 +1,327 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/MishraShardendu22/github-backup/controller"
+	"github.com/MishraShardendu22/github-backup/database"
+	"github.com/MishraShardendu22/github-backup/model"
+	"github.com/MishraShardendu22/github-backup/service/helper"
+	"github.com/MishraShardendu22/github-backup/service/monitor"
+	"github.com/MishraShardendu22/github-backup/util"
+	"go.uber.org/zap"
+)
+
+// exportCursorOverlap re-requests a little before the stored cursor so records updated while the
+// previous export was running are not missed; duplicates are merged away by ID
+const exportCursorOverlap = 5 * time.Minute
+
+// maxExportLineSize bounds a single NDJSON record when reading a previous export back
+const maxExportLineSize = 32 * 1024 * 1024
+
+// exportResource is one listing written as <resource>.ndjson into <owner>/<repo>.meta.tar.gz
+type exportResource struct {
+	name string
+	// query is appended to /repos/<owner>/<repo>; since= and page= are added by the exporter
+	query string
+	// incremental listings accept since= and are merged into the previous export by ID;
+	// the others are small and replaced on every run so deleted labels and milestones disappear
+	incremental bool
+}
+
+var exportResources = []exportResource{
+	{name: "issues", query: "/issues?state=all&sort=updated&direction=asc&per_page=100", incremental: true},
+	{name: "issue_comments", query: "/issues/comments?sort=updated&direction=asc&per_page=100", incremental: true},
+	{name: "review_comments", query: "/pulls/comments?sort=updated&direction=asc&per_page=100", incremental: true},
+	{name: "labels", query: "/labels?per_page=100"},
+	{name: "milestones", query: "/milestones?state=all&per_page=100"},
+}
+
+// pulls has no since= parameter, so it is read newest-first and paging stops at the cursor.
+// reviews are fetched per pull request for the pull requests that changed.
+const (
+	pullsResource   = "pulls"
+	reviewsResource = "reviews"
+	pullsQuery      = "/pulls?state=all&sort=updated&direction=desc&per_page=100"
+)
+
+// exportIssueTrackers exports issues, pull requests, comments, reviews, labels and milestones of every
+// GitHub repo into a sibling <owner>/<repo>.meta.tar.gz. Each repo keeps a cursor in SQLite so later runs
+// only request what changed since; the changes are merged into the previous export.
+func exportIssueTrackers(repos []model.Repo, config *model.ConfigModel, db *sql.DB) {
+	if db == nil {
+		return
+	}
+
+	mon := monitor.Get()
+	exported, failed := 0, 0
+	for _, repo := range repos {
+		if repo.GitHubID() == 0 || repo.WikiOf != "" {
+			continue
+		}
+
+		source, ok := sourceFor(config, repo)
+		if !ok {
+			continue
+		}
+
+		changed, err := exportRepoIssueTracker(repo, source, db)
+		if err != nil {
+			failed++
+			util.Logger().Warn("Failed to export issues and pull requests",
+				zap.String("repository", repo.FullName),
+				zap.Error(err),
+			)
+			if mon != nil {
+				mon.Log("warn", "Issue and pull request export failed: "+err.Error(), repo.FullName)
+			}
+			continue
+		}
+		if changed {
+			exported++
+		}
+	}
+
+	util.Logger().Info("Issue and pull request export complete",
+		zap.Int("updated", exported),
+		zap.Int("failed", failed),
+	)
+	if mon != nil && (exported > 0 || failed > 0) {
+		mon.Log("info", fmt.Sprintf("Issue and pull request export: %d updated, %d failed", exported, failed), "")
+	}
+
+	if exported == 0 {
+		return
+	}
+
+	commitMsg := helper.SanitizeCommitMessage(fmt.Sprintf("Exported issues and pull requests for %d repo(s) on %s",
+		exported, time.Now().Format("2006-01-02 Monday 15:04:05")))
+	pushIfCommitted(commitMsg, "issue-export")
+}
+
+// exportRepoIssueTracker refreshes one repo's export and stages it. It reports whether the archive changed.
+func exportRepoIssueTracker(repo model.Repo, source model.Source, db *sql.DB) (bool, error) {
+	started := time.Now().UTC()
+	repoPath := helper.RepoPath(repo.FullName)
+	metaArchive := helper.MetaArchiveFileName(repoPath)
+
+	cursor, _, err := database.GetExportCursor(db, repo.FullName)
+	if err != nil {
+		return false, fmt.Errorf("read export cursor: %w", err)
+	}
+
+	workDir, err := os.MkdirTemp("", "issue-export-")
+	if err != nil {
+		return false, err
+	}
+	defer os.RemoveAll(workDir)
+
+	// Without the previous archive there is nothing to merge into, so start over with a full export
+	previous := make(map[string][]byte)
+	if _, statErr := os.Stat("_Repos/" + metaArchive); statErr != nil {
+		cursor = time.Time{}
+	} else if !cursor.IsZero() {
+		previous, err = readPreviousExport(metaArchive, filepath.Join(workDir, "previous"))
+		if err != nil {
+			return false, err
+		}
+	}
+
+	var since time.Time
+	if !cursor.IsZero() {
+		since = cursor.Add(-exportCursorOverlap)
+	}
+
+	token, err := controller.SourceToken(source)
+	if err != nil {
+		return false, fmt.Errorf("token for source %s: %w", source.Name, err)
+	}
+	base := fmt.Sprintf("%s/repos/%s", strings.TrimRight(source.APIURL, "/"), source.RemoteName(repo.FullName))
+
+	current := make(map[string][]byte, len(exportResources)+2)
+	for _, resource := range exportResources {
+		listURL := base + resource.query
+		if resource.incremental && !since.IsZero() {
+			listURL += "&since=" + since.Format(time.RFC3339)
+		}
+
+		records, err := controller.ExportController(listURL+"&page=", token, time.Time{})
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", resource.name, err)
+		}
+
+		if resource.incremental {
+			records, err = mergeExportRecords(previous[resource.name], records)
+			if err != nil {
+				return false, fmt.Errorf("%s: %w", resource.name, err)
+			}
+		}
+		if current[resource.name], err = encodeExportRecords(records); err != nil {
+			return false, fmt.Errorf("%s: %w", resource.name, err)
+		}
+	}
+
+	pulls, err := controller.ExportController(base+pullsQuery+"&page=", token, since)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", pullsResource, err)
+	}
+
+	var reviews []model.ExportRecord
+	for _, pull := range pulls {
+		pullReviews, err := controller.ExportController(
+			fmt.Sprintf("%s/pulls/%d/reviews?per_page=100&page=", base, pull.Number), token, time.Time{})
+		if err != nil {
+			return false, fmt.Errorf("%s of #%d: %w", reviewsResource, pull.Number, err)
+		}
+		reviews = append(reviews, pullReviews...)
+	}
+
+	for name, records := range map[string][]model.ExportRecord{pullsResource: pulls, reviewsResource: reviews} {
+		merged, err := mergeExportRecords(previous[name], records)
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", name, err)
+		}
+		if current[name], err = encodeExportRecords(merged); err != nil {
+			return false, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	changed := cursor.IsZero() || len(previous) != len(current)
+	for name, data := range current {
+		if !bytes.Equal(previous[name], data) {
+			changed = true
+		}
+	}
+
+	if changed {
+		if err := writeExportArchive(current, metaArchive, workDir); err != nil {
+			return false, err
+		}
+		if err := helper.StageFiles(metaArchive); err != nil {
+			return false, err
+		}
+		util.Logger().Info("Issues and pull requests exported",
+			zap.String("repository", repo.FullName),
+			zap.Bool("incremental", !since.IsZero()),
+			zap.Int("pulls_updated", len(pulls)),
+		)
+	}
+
+	if err := database.SaveExportCursor(db, repo.FullName, started); err != nil {
+		return changed, fmt.Errorf("save export cursor: %w", err)
+	}
+
+	return changed, nil
+}
+
+// readPreviousExport returns the NDJSON files of an existing export keyed by resource name. The archive
+// root is not assumed, since a renamed repo keeps the directory name it was exported under.
+func readPreviousExport(metaArchive string, dest string) (map[string][]byte, error) {
+	if err := helper.ExtractArchive(metaArchive, dest); err != nil {
+		return nil, err
+	}
+
+	files, err := filepath.Glob(filepath.Join(dest, "*", "*.ndjson"))
+	if err != nil {
+		return nil, err
+	}
+
+	previous := make(map[string][]byte, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		previous[strings.TrimSuffix(filepath.Base(file), ".ndjson")] = data
+	}
+
+	return previous, nil
+}
+
+// mergeExportRecords overlays updated records onto a previous NDJSON export by ID
+func mergeExportRecords(previous []byte, updates []model.ExportRecord) ([]model.ExportRecord, error) {
+	byID := make(map[int64]model.ExportRecord, len(updates))
+
+	scanner := bufio.NewScanner(bytes.NewReader(previous))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxExportLineSize)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var record model.ExportRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			return nil, fmt.Errorf("decode previous export: %w", err)
+		}
+		record.Raw = append(json.RawMessage(nil), line...)
+		byID[record.ID] = record
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read previous export: %w", err)
+	}
+
+	for _, record := range updates {
+		byID[record.ID] = record
+	}
+
+	merged := make([]model.ExportRecord, 0, len(byID))
+	for _, record := range byID {
+		merged = append(merged, record)
+	}
+	return merged, nil
+}
+
+// encodeExportRecords writes one compact JSON object per line, ordered by ID so unchanged data encodes identically
+func encodeExportRecords(records []model.ExportRecord) ([]byte, error) {
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+
+	var buf bytes.Buffer
+	for _, record := range records {
+		if err := json.Compact(&buf, record.Raw); err != nil {
+			return nil, fmt.Errorf("encode record %d: %w", record.ID, err)
+		}
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// writeExportArchive writes every resource as <repo>.meta/<resource>.ndjson and tars the directory into
+// the sibling archive, refusing archives the backup remote would reject
+func writeExportArchive(files map[string][]byte, metaArchive string, workDir string) error {
+	exportDir := filepath.Join(workDir, strings.TrimSuffix(path.Base(metaArchive), ".tar.gz"))
+	if err := os.MkdirAll(exportDir, 0o755); err != nil {
+		return err
+	}
+
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(exportDir, name+".ndjson"), data, 0o644); err != nil {
+			return err
+		}
+	}
+
+	staged := metaArchive + ".tmp"
+	if err := helper.ArchiveDirectory(exportDir, staged); err != nil {
+		return err
+	}
+
+	info, err := os.Stat("_Repos/" + staged)
+	if err != nil {
+		return err
+	}
+	if info.Size() > maxGitHubBlobSize {
+		os.Remove("_Repos/" + staged)
+		return fmt.Errorf("export archive exceeds GitHub blob limit (%d MB)", info.Size()/(1024*1024))
+	}
+
+	return os.Rename("_Repos/"+staged, "_Repos/"+metaArchive)
+}
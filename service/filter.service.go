@@ -0,0 +1,221 @@
+package service
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/MishraShardendu22/github-backup/model"
+	"github.com/MishraShardendu22/github-backup/service/monitor"
+	"github.com/MishraShardendu22/github-backup/util"
+	"go.uber.org/zap"
+)
+
+type excludedRepo struct {
+	Repo   model.Repo
+	Reason string
+}
+
+// repoFilter is FilterRules with the name patterns compiled once per run
+type repoFilter struct {
+	rules        model.FilterRules
+	includeNames []namePattern
+	excludeNames []namePattern
+}
+
+type namePattern struct {
+	raw   string
+	glob  string
+	regex *regexp.Regexp
+}
+
+func newRepoFilter(rules model.FilterRules) (*repoFilter, error) {
+	includeNames, err := compileNamePatterns(rules.IncludeNames)
+	if err != nil {
+		return nil, err
+	}
+	excludeNames, err := compileNamePatterns(rules.ExcludeNames)
+	if err != nil {
+		return nil, err
+	}
+
+	return &repoFilter{rules: rules, includeNames: includeNames, excludeNames: excludeNames}, nil
+}
+
+// compileNamePatterns compiles glob and re: name patterns. The config loader already rejects invalid
+// regexes, so an error here means the rules did not come through it.
+func compileNamePatterns(patterns []string) ([]namePattern, error) {
+	compiled := make([]namePattern, 0, len(patterns))
+	for _, raw := range patterns {
+		if expr, ok := strings.CutPrefix(raw, "re:"); ok {
+			re, err := regexp.Compile("(?i)" + expr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", raw, err)
+			}
+			compiled = append(compiled, namePattern{raw: raw, regex: re})
+			continue
+		}
+
+		compiled = append(compiled, namePattern{raw: raw, glob: strings.ToLower(raw)})
+	}
+
+	return compiled, nil
+}
+
+func (p namePattern) matches(repo model.Repo) bool {
+	candidates := []string{repo.FullName, repo.Name}
+	for _, candidate := range candidates {
+		if candidate == "" {
+			continue
+		}
+		if p.regex != nil {
+			if p.regex.MatchString(candidate) {
+				return true
+			}
+			continue
+		}
+		if ok, _ := path.Match(p.glob, strings.ToLower(candidate)); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// exclusionReason returns why the repo is filtered out, or "" when it should be backed up
+func (f *repoFilter) exclusionReason(repo model.Repo) string {
+	rules := f.rules
+	owner := repoOwner(repo)
+
+	if rules.SkipForks && repo.Fork {
+		return "fork"
+	}
+	if rules.SkipArchived && repo.Archived {
+		return "archived"
+	}
+	if rules.SkipDisabled && repo.Disabled {
+		return "disabled"
+	}
+	if len(rules.IncludeOwners) > 0 && !containsFold(rules.IncludeOwners, owner) {
+		return fmt.Sprintf("owner %q not included", owner)
+	}
+	if containsFold(rules.ExcludeOwners, owner) {
+		return fmt.Sprintf("owner %q excluded", owner)
+	}
+	if len(f.includeNames) > 0 && matchingPattern(f.includeNames, repo) == "" {
+		return "name not included"
+	}
+	if pattern := matchingPattern(f.excludeNames, repo); pattern != "" {
+		return fmt.Sprintf("name matches %q", pattern)
+	}
+	if visibility := repoVisibility(repo); len(rules.Visibilities) > 0 && !containsFold(rules.Visibilities, visibility) {
+		return fmt.Sprintf("visibility %q not included", visibility)
+	}
+	if len(rules.IncludeLanguages) > 0 && !containsFold(rules.IncludeLanguages, repo.Language) {
+		return fmt.Sprintf("language %q not included", repo.Language)
+	}
+	if repo.Language != "" && containsFold(rules.ExcludeLanguages, repo.Language) {
+		return fmt.Sprintf("language %q excluded", repo.Language)
+	}
+	if len(rules.IncludeTopics) > 0 && !anyContainsFold(rules.IncludeTopics, repo.Topics) {
+		return "no included topic"
+	}
+	for _, topic := range repo.Topics {
+		if containsFold(rules.ExcludeTopics, topic) {
+			return fmt.Sprintf("topic %q excluded", topic)
+		}
+	}
+
+	return ""
+}
+
+func filterRepos(repos []model.Repo, rules model.FilterRules) ([]model.Repo, []excludedRepo, error) {
+	filter, err := newRepoFilter(rules)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	kept := make([]model.Repo, 0, len(repos))
+	var excluded []excludedRepo
+	for _, repo := range repos {
+		if reason := filter.exclusionReason(repo); reason != "" {
+			excluded = append(excluded, excludedRepo{Repo: repo, Reason: reason})
+			continue
+		}
+		kept = append(kept, repo)
+	}
+
+	return kept, excluded, nil
+}
+
+func reportExcludedRepos(excluded []excludedRepo) {
+	if len(excluded) == 0 {
+		return
+	}
+
+	mon := monitor.Get()
+	for _, ex := range excluded {
+		util.Logger().Info("Repository excluded by filter rules",
+			zap.String("repository", ex.Repo.FullName),
+			zap.String("reason", ex.Reason),
+		)
+		if mon != nil {
+			mon.Log("info", "Excluded by filter rules: "+ex.Reason, ex.Repo.FullName)
+		}
+	}
+
+	util.Logger().Info("Filter rules applied",
+		zap.Int("excluded", len(excluded)),
+	)
+	if mon != nil {
+		mon.Log("info", fmt.Sprintf("%d repositories excluded by filter rules", len(excluded)), "")
+	}
+}
+
+func repoOwner(repo model.Repo) string {
+	if repo.Owner.Login != "" {
+		return repo.Owner.Login
+	}
+	if owner, _, ok := strings.Cut(repo.FullName, "/"); ok {
+		return owner
+	}
+	return ""
+}
+
+func repoVisibility(repo model.Repo) string {
+	if repo.Visibility != "" {
+		return repo.Visibility
+	}
+	if repo.Private {
+		return "private"
+	}
+	return "public"
+}
+
+func matchingPattern(patterns []namePattern, repo model.Repo) string {
+	for _, pattern := range patterns {
+		if pattern.matches(repo) {
+			return pattern.raw
+		}
+	}
+	return ""
+}
+
+func containsFold(values []string, target string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func anyContainsFold(values []string, targets []string) bool {
+	for _, target := range targets {
+		if containsFold(values, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/MishraShardendu22/github-backup/model"
+)
+
+func TestCompileNamePatterns(t *testing.T) {
+	patterns, err := compileNamePatterns([]string{"acme/*", "re:^infra-", "Web"})
+	if err != nil || len(patterns) != 3 {
+		t.Fatalf("compiled %d patterns, %v; want 3", len(patterns), err)
+	}
+
+	tests := []struct {
+		repo model.Repo
+		want string
+	}{
+		{repo: model.Repo{FullName: "acme/api", Name: "api"}, want: "acme/*"},
+		{repo: model.Repo{FullName: "ACME/API", Name: "API"}, want: "acme/*"},
+		{repo: model.Repo{FullName: "other/infra-dns", Name: "infra-dns"}, want: "re:^infra-"},
+		{repo: model.Repo{FullName: "other/INFRA-dns", Name: "INFRA-dns"}, want: "re:^infra-"},
+		{repo: model.Repo{FullName: "other/web", Name: "web"}, want: "Web"},
+		{repo: model.Repo{FullName: "other/dns-infra", Name: "dns-infra"}, want: ""},
+	}
+	for _, tt := range tests {
+		if got := matchingPattern(patterns, tt.repo); got != tt.want {
+			t.Errorf("matchingPattern(%s) = %q, want %q", tt.repo.FullName, got, tt.want)
+		}
+	}
+}
+
+func TestExclusionReason(t *testing.T) {
+	repo := model.Repo{
+		FullName:   "acme/api",
+		Name:       "api",
+		Owner:      model.Owner{Login: "acme"},
+		Language:   "Go",
+		Visibility: "private",
+		Topics:     []string{"backend", "legacy"},
+	}
+
+	tests := []struct {
+		name  string
+		rules model.FilterRules
+		repo  func(model.Repo) model.Repo
+		want  string
+	}{
+		{name: "no rules", want: ""},
+		{name: "fork skipped", rules: model.FilterRules{SkipForks: true}, repo: func(r model.Repo) model.Repo { r.Fork = true; return r }, want: "fork"},
+		{name: "archived skipped", rules: model.FilterRules{SkipArchived: true}, repo: func(r model.Repo) model.Repo { r.Archived = true; return r }, want: "archived"},
+		{name: "owner not included", rules: model.FilterRules{IncludeOwners: []string{"other"}}, want: `owner "acme" not included`},
+		{name: "owner included case-insensitively", rules: model.FilterRules{IncludeOwners: []string{"ACME"}}, want: ""},
+		{name: "owner excluded", rules: model.FilterRules{ExcludeOwners: []string{"acme"}}, want: `owner "acme" excluded`},
+		{name: "name not included", rules: model.FilterRules{IncludeNames: []string{"re:^web"}}, want: "name not included"},
+		{name: "name included", rules: model.FilterRules{IncludeNames: []string{"re:^web", "acme/a*"}}, want: ""},
+		{name: "name excluded", rules: model.FilterRules{ExcludeNames: []string{"*/api"}}, want: `name matches "*/api"`},
+		{name: "visibility", rules: model.FilterRules{Visibilities: []string{"public"}}, want: `visibility "private" not included`},
+		{name: "language not included", rules: model.FilterRules{IncludeLanguages: []string{"rust"}}, want: `language "Go" not included`},
+		{name: "language excluded", rules: model.FilterRules{ExcludeLanguages: []string{"go"}}, want: `language "Go" excluded`},
+		{name: "no included topic", rules: model.FilterRules{IncludeTopics: []string{"frontend"}}, want: "no included topic"},
+		{name: "topic excluded", rules: model.FilterRules{ExcludeTopics: []string{"LEGACY"}}, want: `topic "legacy" excluded`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candidate := repo
+			if tt.repo != nil {
+				candidate = tt.repo(repo)
+			}
+			filter, err := newRepoFilter(tt.rules)
+			if err != nil {
+				t.Fatalf("newRepoFilter: %v", err)
+			}
+			if got := filter.exclusionReason(candidate); got != tt.want {
+				t.Errorf("exclusionReason = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInvalidNamePattern(t *testing.T) {
+	if _, err := compileNamePatterns([]string{"acme/*", "re:(unclosed"}); err == nil || !strings.Contains(err.Error(), "re:(unclosed") {
+		t.Errorf("compileNamePatterns err = %v, want the invalid pattern named", err)
+	}
+
+	repos := []model.Repo{{FullName: "acme/api", Name: "api"}}
+	kept, excluded, err := filterRepos(repos, model.FilterRules{IncludeNames: []string{"re:[a-"}})
+	if err == nil || kept != nil || excluded != nil {
+		t.Errorf("filterRepos = %v, %v, %v; want an error and no repos", kept, excluded, err)
+	}
+
+	config := &model.ConfigModel{BackupModeRules: []model.BackupModeRule{{Pattern: "re:*", Mode: model.BackupModeMirror}}}
+	if _, err := newBackupModes(config); err == nil {
+		t.Error("newBackupModes accepted an invalid pattern")
+	}
+}
@@ -0,0 +1,515 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/MishraShardendu22/github-backup/controller"
+	"github.com/MishraShardendu22/github-backup/model"
+	"github.com/MishraShardendu22/github-backup/service/helper"
+	"github.com/MishraShardendu22/github-backup/service/monitor"
+	"github.com/MishraShardendu22/github-backup/util"
+	"go.uber.org/zap"
+)
+
+// snapshotGovernance writes the teams, members, collaborators, branch protection, rulesets, webhooks,
+// deploy keys and Actions secret names of every GitHub org source to _Repos/_governance/<org>. The files
+// are stable, sorted JSON, so the backup repo's history doubles as a permission audit trail; sections that
+// changed since the last run are reported to the monitor.
+func snapshotGovernance(discovery DiscoveryResult, config *model.ConfigModel) {
+	failed := make(map[string]bool, len(discovery.FailedSources))
+	for _, name := range discovery.FailedSources {
+		failed[name] = true
+	}
+
+	mon := monitor.Get()
+	for _, source := range config.Sources {
+		if source.Provider != model.ProviderGitHub || source.Kind != model.SourceKindOrg {
+			continue
+		}
+
+		// A partial repo list would read as repos vanishing from the snapshot
+		if failed[source.Name] {
+			util.Logger().Warn("Skipping governance snapshot; discovery failed for source",
+				zap.String("source", source.Name),
+				zap.String("org", source.Account),
+			)
+			continue
+		}
+
+		var repos []model.Repo
+		for _, repo := range discovery.Present() {
+			if repo.Source == source.Name && repo.GitHubID() != 0 && repo.WikiOf == "" {
+				repos = append(repos, repo)
+			}
+		}
+
+		if err := snapshotOrgGovernance(source, repos); err != nil {
+			util.Logger().Warn("Failed to snapshot organization governance",
+				zap.String("org", source.Account),
+				zap.Error(err),
+			)
+			if mon != nil {
+				mon.Log("warn", fmt.Sprintf("Governance snapshot of %s failed: %v", source.Account, err), "")
+			}
+			continue
+		}
+
+		commitMsg := helper.SanitizeCommitMessage(fmt.Sprintf("Governance snapshot on %s for the org %s",
+			time.Now().Format("2006-01-02 Monday 15:04:05"), source.Account))
+		pushIfCommitted(commitMsg, "governance "+source.Account)
+	}
+}
+
+func snapshotOrgGovernance(source model.Source, repos []model.Repo) error {
+	token, err := controller.SourceToken(source)
+	if err != nil {
+		return fmt.Errorf("token for source %s: %w", source.Name, err)
+	}
+
+	fetcher := &governanceFetcher{base: strings.TrimRight(source.APIURL, "/"), token: token}
+	org, err := fetcher.org(source.Account)
+	if err != nil {
+		return err
+	}
+
+	// Everything is fetched before anything is written, so a failed snapshot leaves no half-updated files
+	// behind for the next run to commit without reporting them
+	dir := helper.GovernancePath(source.Account)
+	files := []governanceFile{{file: path.Join(dir, "org.json"), doc: org}}
+	for _, repo := range repos {
+		remoteName := source.RemoteName(repo.FullName)
+		doc, err := fetcher.repo(remoteName)
+		if err != nil {
+			return fmt.Errorf("%s: %w", repo.FullName, err)
+		}
+
+		files = append(files, governanceFile{
+			file: path.Join(dir, "repos", helper.ExtractRepoName(remoteName)+".json"),
+			doc:  doc,
+			repo: repo.FullName,
+		})
+	}
+
+	written := make(map[string]bool, len(files))
+	for _, f := range files {
+		if err := writeGovernanceFile(f.file, f.doc, f.repo); err != nil {
+			return err
+		}
+		written[f.file] = true
+	}
+
+	// Repos that left the org (or were renamed) drop out of the snapshot; git history keeps their last state
+	stale, err := filepath.Glob(filepath.Join("_Repos", dir, "repos", "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, file := range stale {
+		relative := strings.TrimPrefix(filepath.ToSlash(file), "_Repos/")
+		if written[relative] {
+			continue
+		}
+		if err := os.Remove(file); err != nil {
+			return err
+		}
+		util.Logger().Info("Governance snapshot removed for repository no longer in org",
+			zap.String("file", relative),
+		)
+	}
+
+	return helper.StageFiles(dir)
+}
+
+// governanceFile is one fetched snapshot document waiting to be written; repo is the monitor's repository
+// field, empty for org-wide files
+type governanceFile struct {
+	file string
+	doc  any
+	repo string
+}
+
+// governanceFetcher reads one snapshot. 403 and 404 mark a section unavailable, since most settings need
+// admin rights; any other failure aborts the snapshot so a half-read org never shows up as a permission change.
+type governanceFetcher struct {
+	base        string
+	token       string
+	unavailable map[string]string
+}
+
+func (f *governanceFetcher) org(org string) (model.OrgGovernance, error) {
+	f.unavailable = make(map[string]string)
+	doc := model.OrgGovernance{FormatVersion: model.GovernanceFormatVersion, Org: org}
+	prefix := "/orgs/" + url.PathEscape(org)
+
+	for _, role := range []string{"admin", "member"} {
+		var members []model.OrgMember
+		if err := f.list("members", prefix+"/members?role="+role, "", &members); err != nil {
+			return doc, err
+		}
+		for i := range members {
+			members[i].Role = role
+		}
+		doc.Members = append(doc.Members, members...)
+	}
+	sort.Slice(doc.Members, func(i, j int) bool { return doc.Members[i].Login < doc.Members[j].Login })
+
+	if err := f.list("outside_collaborators", prefix+"/outside_collaborators", "", &doc.OutsideCollaborators); err != nil {
+		return doc, err
+	}
+	sort.Slice(doc.OutsideCollaborators, func(i, j int) bool {
+		return doc.OutsideCollaborators[i].Login < doc.OutsideCollaborators[j].Login
+	})
+
+	teams, err := f.teams(prefix)
+	if err != nil {
+		return doc, err
+	}
+	doc.Teams = teams
+
+	if doc.Webhooks, err = f.webhooks("webhooks", prefix+"/hooks"); err != nil {
+		return doc, err
+	}
+	if doc.Rulesets, err = f.rulesets(prefix + "/rulesets"); err != nil {
+		return doc, err
+	}
+	if doc.ActionsSecrets, err = f.secrets(prefix + "/actions/secrets"); err != nil {
+		return doc, err
+	}
+
+	doc.Unavailable = f.unavailable
+	return doc, nil
+}
+
+func (f *governanceFetcher) repo(fullName string) (model.RepoGovernance, error) {
+	f.unavailable = make(map[string]string)
+	doc := model.RepoGovernance{
+		FormatVersion:    model.GovernanceFormatVersion,
+		Repo:             fullName,
+		BranchProtection: make(map[string]any),
+	}
+	prefix := "/repos/" + fullName
+
+	if err := f.list("collaborators", prefix+"/collaborators?affiliation=all", "", &doc.Collaborators); err != nil {
+		return doc, err
+	}
+	sort.Slice(doc.Collaborators, func(i, j int) bool { return doc.Collaborators[i].Login < doc.Collaborators[j].Login })
+
+	var branches []struct {
+		Name string `json:"name"`
+	}
+	if err := f.list("branch_protection", prefix+"/branches?protected=true", "", &branches); err != nil {
+		return doc, err
+	}
+	for _, branch := range branches {
+		var protection any
+		found, err := f.document("branch_protection", prefix+"/branches/"+url.PathEscape(branch.Name)+"/protection", &protection)
+		if err != nil {
+			return doc, err
+		}
+		if found {
+			doc.BranchProtection[branch.Name] = protection
+		}
+	}
+
+	var err error
+	if doc.Rulesets, err = f.rulesets(prefix + "/rulesets?includes_parents=false"); err != nil {
+		return doc, err
+	}
+	if doc.Webhooks, err = f.webhooks("webhooks", prefix+"/hooks"); err != nil {
+		return doc, err
+	}
+
+	var keys []struct {
+		model.DeployKey
+		Key string `json:"key"`
+	}
+	if err := f.list("deploy_keys", prefix+"/keys", "", &keys); err != nil {
+		return doc, err
+	}
+	if keys != nil {
+		doc.DeployKeys = make([]model.DeployKey, 0, len(keys))
+	}
+	for _, key := range keys {
+		deployKey := key.DeployKey
+		deployKey.Fingerprint = sshKeyFingerprint(key.Key)
+		doc.DeployKeys = append(doc.DeployKeys, deployKey)
+	}
+	sort.Slice(doc.DeployKeys, func(i, j int) bool { return doc.DeployKeys[i].ID < doc.DeployKeys[j].ID })
+
+	if doc.ActionsSecrets, err = f.secrets(prefix + "/actions/secrets"); err != nil {
+		return doc, err
+	}
+
+	doc.Unavailable = f.unavailable
+	return doc, nil
+}
+
+func (f *governanceFetcher) teams(prefix string) ([]model.Team, error) {
+	var apiTeams []struct {
+		model.Team
+		Parent *struct {
+			Slug string `json:"slug"`
+		} `json:"parent"`
+	}
+	if err := f.list("teams", prefix+"/teams", "", &apiTeams); err != nil {
+		return nil, err
+	}
+
+	teams := make([]model.Team, 0, len(apiTeams))
+	for _, apiTeam := range apiTeams {
+		team := apiTeam.Team
+		if apiTeam.Parent != nil {
+			team.Parent = apiTeam.Parent.Slug
+		}
+		teamPrefix := prefix + "/teams/" + url.PathEscape(team.Slug)
+
+		var err error
+		if team.Maintainers, err = f.logins("teams", teamPrefix+"/members?role=maintainer"); err != nil {
+			return nil, err
+		}
+		if team.Members, err = f.logins("teams", teamPrefix+"/members?role=member"); err != nil {
+			return nil, err
+		}
+		if err := f.list("teams", teamPrefix+"/repos", "", &team.Repos); err != nil {
+			return nil, err
+		}
+		sort.Slice(team.Repos, func(i, j int) bool { return team.Repos[i].FullName < team.Repos[j].FullName })
+
+		teams = append(teams, team)
+	}
+
+	sort.Slice(teams, func(i, j int) bool { return teams[i].Slug < teams[j].Slug })
+	return teams, nil
+}
+
+func (f *governanceFetcher) logins(section string, listPath string) ([]string, error) {
+	var users []struct {
+		Login string `json:"login"`
+	}
+	if err := f.list(section, listPath, "", &users); err != nil {
+		return nil, err
+	}
+
+	logins := make([]string, 0, len(users))
+	for _, user := range users {
+		logins = append(logins, user.Login)
+	}
+	sort.Strings(logins)
+	return logins, nil
+}
+
+// webhooks keeps hook configurations without their secret, only recording whether one is set.
+// Delivery status is dropped; it changes with every delivery and would make every snapshot a diff.
+func (f *governanceFetcher) webhooks(section string, listPath string) ([]any, error) {
+	var hooks []map[string]any
+	if err := f.list(section, listPath, "", &hooks); err != nil {
+		return nil, err
+	}
+	if hooks == nil {
+		return nil, nil
+	}
+
+	scrubbed := make([]any, 0, len(hooks))
+	for _, hook := range hooks {
+		delete(hook, "last_response")
+		if config, ok := hook["config"].(map[string]any); ok {
+			_, hasSecret := config["secret"]
+			delete(config, "secret")
+			config["secret_configured"] = hasSecret
+		}
+		scrubbed = append(scrubbed, hook)
+	}
+
+	sortByID(scrubbed)
+	return scrubbed, nil
+}
+
+// rulesets reads every ruleset in full; the listing leaves out the rules themselves
+func (f *governanceFetcher) rulesets(listPath string) ([]any, error) {
+	var summaries []struct {
+		ID int `json:"id"`
+	}
+	if err := f.list("rulesets", listPath, "", &summaries); err != nil {
+		return nil, err
+	}
+	if summaries == nil {
+		return nil, nil
+	}
+
+	base, _, _ := strings.Cut(listPath, "?")
+	rulesets := make([]any, 0, len(summaries))
+	for _, summary := range summaries {
+		var ruleset any
+		found, err := f.document("rulesets", fmt.Sprintf("%s/%d", base, summary.ID), &ruleset)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			rulesets = append(rulesets, ruleset)
+		}
+	}
+
+	sortByID(rulesets)
+	return rulesets, nil
+}
+
+func (f *governanceFetcher) secrets(listPath string) ([]model.SecretName, error) {
+	var secrets []model.SecretName
+	if err := f.list("actions_secrets", listPath, "secrets", &secrets); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(secrets, func(i, j int) bool { return secrets[i].Name < secrets[j].Name })
+	return secrets, nil
+}
+
+// list pages through a listing into out. An unavailable section leaves out untouched and returns nil.
+func (f *governanceFetcher) list(section string, listPath string, envelope string, out any) error {
+	separator := "?"
+	if strings.Contains(listPath, "?") {
+		separator = "&"
+	}
+
+	items, err := controller.GovernanceController(f.base+listPath+separator+"per_page=100&page=", f.token, envelope)
+	if err != nil {
+		return f.unavailableOr(section, err)
+	}
+	if items == nil {
+		items = []json.RawMessage{}
+	}
+
+	data, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	return decodeGovernance(data, out)
+}
+
+// document fetches a single object into out and reports whether it was available
+func (f *governanceFetcher) document(section string, documentPath string, out any) (bool, error) {
+	data, err := controller.GovernanceDocument(f.base+documentPath, f.token)
+	if err != nil {
+		return false, f.unavailableOr(section, err)
+	}
+	return true, decodeGovernance(data, out)
+}
+
+func (f *governanceFetcher) unavailableOr(section string, err error) error {
+	var statusErr *controller.APIStatusError
+	if errors.As(err, &statusErr) &&
+		(statusErr.StatusCode == http.StatusForbidden || statusErr.StatusCode == http.StatusNotFound) {
+		f.unavailable[section] = fmt.Sprintf("status %d", statusErr.StatusCode)
+		return nil
+	}
+	return fmt.Errorf("%s: %w", section, err)
+}
+
+// decodeGovernance keeps numbers as json.Number so IDs in untyped sections survive a round trip unchanged
+func decodeGovernance(data []byte, out any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(out)
+}
+
+func sortByID(items []any) {
+	id := func(item any) int64 {
+		if object, ok := item.(map[string]any); ok {
+			if number, ok := object["id"].(json.Number); ok {
+				value, _ := number.Int64()
+				return value
+			}
+		}
+		return 0
+	}
+	sort.SliceStable(items, func(i, j int) bool { return id(items[i]) < id(items[j]) })
+}
+
+// sshKeyFingerprint returns the OpenSSH SHA256 fingerprint of an authorized_keys style public key
+func sshKeyFingerprint(key string) string {
+	fields := strings.Fields(key)
+	if len(fields) < 2 {
+		return ""
+	}
+
+	blob, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(blob)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// writeGovernanceFile writes a snapshot file inside _Repos and reports which top-level sections differ
+// from the previous snapshot. repo is the monitor's repository field, empty for org-wide files.
+func writeGovernanceFile(file string, doc any, repo string) error {
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	fullPath := "_Repos/" + file
+	previous, readErr := os.ReadFile(fullPath)
+	if readErr == nil && bytes.Equal(previous, data) {
+		return nil
+	}
+
+	if err := os.MkdirAll(path.Dir(fullPath), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(fullPath, data, 0o644); err != nil {
+		return err
+	}
+
+	if readErr != nil {
+		util.Logger().Info("Governance snapshot created", zap.String("file", file))
+		return nil
+	}
+
+	sections := changedSections(previous, data)
+	util.Logger().Warn("Governance settings changed since last snapshot",
+		zap.String("file", file),
+		zap.Strings("sections", sections),
+	)
+	if mon := monitor.Get(); mon != nil {
+		mon.Log("warn", fmt.Sprintf("Governance changed in %s: %s", file, strings.Join(sections, ", ")), repo)
+	}
+
+	return nil
+}
+
+// changedSections lists the top-level keys whose values differ between two snapshot files
+func changedSections(previous []byte, current []byte) []string {
+	var before, after map[string]json.RawMessage
+	if json.Unmarshal(previous, &before) != nil || json.Unmarshal(current, &after) != nil {
+		return []string{"file"}
+	}
+
+	var sections []string
+	for key, value := range after {
+		if !bytes.Equal(before[key], value) {
+			sections = append(sections, key)
+		}
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			sections = append(sections, key)
+		}
+	}
+
+	sort.Strings(sections)
+	return sections
+}
@@ -0,0 +1,49 @@
+package service
+
+import "testing"
+
+func TestSSHKeyFingerprint(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		want string
+	}{
+		{
+			name: "ed25519 with comment",
+			key:  "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIB+Msox8b32/5UbJQ0VqT4Nhy730/CNaQ5OrRlkyezkI deploy@example",
+			want: "SHA256:4BmzwbNpLYd7F1YNCAeM/HnVQi6gWFegjqQ0lpl0jGk",
+		},
+		{
+			name: "without comment",
+			key:  "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIB+Msox8b32/5UbJQ0VqT4Nhy730/CNaQ5OrRlkyezkI",
+			want: "SHA256:4BmzwbNpLYd7F1YNCAeM/HnVQi6gWFegjqQ0lpl0jGk",
+		},
+		{name: "type only", key: "ssh-ed25519", want: ""},
+		{name: "empty", key: "", want: ""},
+		{name: "invalid base64", key: "ssh-rsa not*base64", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sshKeyFingerprint(tt.key); got != tt.want {
+				t.Errorf("sshKeyFingerprint(%q) = %q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChangedSections(t *testing.T) {
+	previous := []byte(`{"teams":[1],"webhooks":[],"members":["a"]}`)
+	current := []byte(`{"teams":[1,2],"webhooks":[],"rulesets":[]}`)
+
+	got := changedSections(previous, current)
+	want := []string{"members", "rulesets", "teams"}
+	if len(got) != len(want) {
+		t.Fatalf("changedSections = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("changedSections = %v, want %v", got, want)
+		}
+	}
+}
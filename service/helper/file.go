@@ -0,0 +1,64 @@
+package helper
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+)
+
+// FileSHA256 returns the hex SHA-256 of a file
+func FileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// SplitFile cuts a file into <path>.part-000, <path>.part-001, ... of at most partSize bytes and removes
+// the original. Concatenating the parts in order restores it.
+func SplitFile(path string, partSize int64) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var parts []string
+	for index := 0; ; index++ {
+		partPath := fmt.Sprintf("%s.part-%03d", path, index)
+		part, err := os.Create(partPath)
+		if err != nil {
+			return parts, err
+		}
+
+		written, copyErr := io.CopyN(part, file, partSize)
+		closeErr := part.Close()
+		if copyErr != nil && copyErr != io.EOF {
+			return parts, fmt.Errorf("split %s: %w", path, copyErr)
+		}
+		if closeErr != nil {
+			return parts, closeErr
+		}
+		if written == 0 {
+			os.Remove(partPath)
+			break
+		}
+		parts = append(parts, partPath)
+		if copyErr == io.EOF {
+			break
+		}
+	}
+
+	file.Close()
+	return parts, os.Remove(path)
+}
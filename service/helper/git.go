@@ -1,9 +1,15 @@
 package helper
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"path"
+	"sort"
 	"strings"
 	"time"
 
@@ -56,73 +62,362 @@ func EnsureBackupRepoInitialized(config *model.ConfigModel) error {
 	}, "Initial git setup", pushTimeout)
 }
 
-func GetRemoteHeadHash(repoURL string) (string, error) {
+// GitTokenEnv authenticates HTTPS git commands with a token through an extra header passed in the
+// environment, so the token never appears in the remote URL, argv or the repos table
+func GitTokenEnv(token string) []string {
+	if token == "" {
+		return nil
+	}
+
+	credentials := base64.StdEncoding.EncodeToString([]byte("x-access-token:" + token))
+	return []string{
+		"GIT_TERMINAL_PROMPT=0",
+		"GIT_CONFIG_COUNT=1",
+		"GIT_CONFIG_KEY_0=http.extraHeader",
+		"GIT_CONFIG_VALUE_0=Authorization: Basic " + credentials,
+	}
+}
+
+// ErrRemoteMissing is returned by GetRemoteHeadHash and GetRemoteRefs when the remote does not exist or has no commits yet
+var ErrRemoteMissing = errors.New("remote repository not found or empty")
+
+// missingRemoteMessages are what git and the forges print for a repository that does not exist
+var missingRemoteMessages = []string{
+	"not found",
+	"does not appear to be a git repository",
+}
+
+func GetRemoteHeadHash(repoURL string, gitEnv []string) (string, error) {
 	// get latest hash
-	out, err := exec.Command("git", "ls-remote", repoURL, "HEAD").CombinedOutput()
+	out, err := lsRemote(repoURL, gitEnv, "HEAD")
 	if err != nil {
-		return "", fmt.Errorf("git ls-remote failed: %v: %s", err, strings.TrimSpace(string(out)))
+		return "", err
 	}
 
-	fields := strings.Fields(string(out))
+	fields := strings.Fields(out)
 	if len(fields) == 0 {
-		return "", fmt.Errorf("git ls-remote returned no hash")
+		return "", fmt.Errorf("git ls-remote returned no hash: %w", ErrRemoteMissing)
 	}
 
 	return fields[0], nil
 }
 
-func CleanupExistingRepo(repoName string) {
-	cleanupCmd := exec.Command("sh", "-c", fmt.Sprintf("cd _Repos && rm -rf '%s' '%s.tar.gz'", repoName, repoName))
+// GetRemoteRefs returns HEAD, the branches and the tags of a remote mapped to the objects they point at.
+// Peeled tag entries and forge-managed refs such as refs/pull/* are left out, since they never need a backup
+// of their own.
+func GetRemoteRefs(repoURL string, gitEnv []string) (map[string]string, error) {
+	out, err := lsRemote(repoURL, gitEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make(map[string]string)
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || strings.HasSuffix(fields[1], "^{}") {
+			continue
+		}
+		ref := fields[1]
+		if ref == "HEAD" || strings.HasPrefix(ref, "refs/heads/") || strings.HasPrefix(ref, "refs/tags/") {
+			refs[ref] = fields[0]
+		}
+	}
+	if len(refs) == 0 {
+		return nil, fmt.Errorf("git ls-remote returned no refs: %w", ErrRemoteMissing)
+	}
+
+	return refs, nil
+}
+
+// RefsFingerprint hashes a ref map into one value that changes whenever any ref is created, moved or deleted
+func RefsFingerprint(refs map[string]string) string {
+	names := make([]string, 0, len(refs))
+	for ref := range refs {
+		names = append(names, ref)
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	for _, ref := range names {
+		fmt.Fprintf(h, "%s %s\n", refs[ref], ref)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func lsRemote(repoURL string, gitEnv []string, patterns ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"ls-remote", repoURL}, patterns...)...)
+	cmd.Env = append(os.Environ(), gitEnv...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		output := strings.TrimSpace(string(out))
+		for _, message := range missingRemoteMessages {
+			if strings.Contains(output, message) {
+				return "", fmt.Errorf("git ls-remote failed: %w: %s", ErrRemoteMissing, output)
+			}
+		}
+		return "", fmt.Errorf("git ls-remote failed: %v: %s", err, output)
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+func CleanupExistingRepo(repoPath string) {
+	cleanupCmd := exec.Command("sh", "-c", fmt.Sprintf("cd _Repos && rm -rf '%s' '%s'", repoPath, ArchiveFileName(repoPath)))
 	if _, err := cleanupCmd.CombinedOutput(); err != nil {
 		util.Logger().Warn("Repository cleanup failed",
-			zap.String("repository", repoName),
+			zap.String("repository", repoPath),
 			zap.Error(err),
 		)
 	}
 }
 
-func CloneRepo(url string, repoName string) error {
+func CloneRepo(url string, repoPath string, gitEnv []string) error {
 	return retryCommand(func() *exec.Cmd {
 		// Shallow clone the working tree (non-bare) and remove the .git directory so only the latest code remains
-		return exec.Command("sh", "-c", fmt.Sprintf("cd _Repos && git clone --depth=1 '%s' '%s' && rm -rf '%s/.git'", url, repoName, repoName))
-	}, fmt.Sprintf("Clone %s", repoName), cloneTimeout)
+		cmd := exec.Command("sh", "-c", fmt.Sprintf("cd _Repos && mkdir -p '%s' && git clone --depth=1 '%s' '%s' && rm -rf '%s/.git'",
+			path.Dir(repoPath), url, repoPath, repoPath))
+		cmd.Env = append(os.Environ(), gitEnv...)
+		return cmd
+	}, fmt.Sprintf("Clone %s", repoPath), cloneTimeout)
+}
+
+// CloneMirror clones every branch, tag and other ref of url into a bare mirror at dest, replacing what is there
+func CloneMirror(url string, dest string, gitEnv []string) error {
+	return retryCommand(func() *exec.Cmd {
+		cmd := exec.Command("sh", "-c", fmt.Sprintf("rm -rf '%s' && git clone --mirror '%s' '%s'", dest, url, dest))
+		cmd.Env = append(os.Environ(), gitEnv...)
+		return cmd
+	}, fmt.Sprintf("Mirror clone %s", dest), cloneTimeout)
+}
+
+// FetchMirror brings an existing bare mirror up to date with url, pruning branches and tags deleted upstream
+func FetchMirror(mirrorDir string, url string, gitEnv []string) error {
+	return retryCommand(func() *exec.Cmd {
+		cmd := exec.Command("sh", "-c", fmt.Sprintf("git -C '%s' remote set-url origin '%s' && git -C '%s' fetch --prune origin",
+			mirrorDir, url, mirrorDir))
+		cmd.Env = append(os.Environ(), gitEnv...)
+		return cmd
+	}, fmt.Sprintf("Fetch %s", mirrorDir), cloneTimeout)
+}
+
+// SetMirrorHead points a bare mirror's HEAD at branch, since fetching never moves HEAD when the default
+// branch changes upstream. A branch the mirror does not have is ignored.
+func SetMirrorHead(mirrorDir string, branch string) error {
+	ref := "refs/heads/" + branch
+	if exec.Command("git", "-C", mirrorDir, "show-ref", "--verify", "--quiet", ref).Run() != nil {
+		return nil
+	}
+
+	if out, err := exec.Command("git", "-C", mirrorDir, "symbolic-ref", "HEAD", ref).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to set HEAD of %s: %v: %s", mirrorDir, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// ErrEmptyBundle is returned by CreateBundle when the mirror has no objects beyond the excluded ones
+var ErrEmptyBundle = errors.New("no new objects to bundle")
+
+// CreateBundle writes every ref of a mirror into bundle and checks it with git bundle verify. Objects
+// reachable from exclude are left out, making the bundle incremental on top of a bundle holding them.
+// bundle must be an absolute path.
+func CreateBundle(mirrorDir string, bundle string, exclude []string) error {
+	args := []string{"-C", mirrorDir, "bundle", "create", bundle, "--all"}
+	if len(exclude) > 0 {
+		args = append(append(args, "--not"), exclude...)
+	}
+
+	if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+		output := strings.TrimSpace(string(out))
+		if strings.Contains(output, "empty bundle") {
+			return ErrEmptyBundle
+		}
+		return fmt.Errorf("failed to bundle %s: %v: %s", mirrorDir, err, output)
+	}
+
+	verifyCmd := exec.Command("git", "-C", mirrorDir, "bundle", "verify", bundle)
+	if out, err := verifyCmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("bundle verify failed for %s: %v: %s", bundle, err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
 }
 
-func ArchiveRepo(repoName string) error {
-	repoDir := fmt.Sprintf("%s", repoName)
-	archiveName := fmt.Sprintf("%s.tar.gz", repoName)
+// ExistingObjects returns the hashes that are present in a repository
+func ExistingObjects(repoDir string, hashes []string) ([]string, error) {
+	checkCmd := exec.Command("git", "-C", repoDir, "cat-file", "--batch-check=%(objectname)")
+	checkCmd.Stdin = strings.NewReader(strings.Join(hashes, "\n") + "\n")
+	out, err := checkCmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check objects in %s: %v", repoDir, err)
+	}
+
+	var existing []string
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if line != "" && !strings.HasSuffix(line, " missing") {
+			existing = append(existing, line)
+		}
+	}
+	return existing, nil
+}
+
+// MirrorRefs returns every ref of a mirror and the object it points to, plus the ref HEAD points to
+func MirrorRefs(mirrorDir string) (map[string]string, string, error) {
+	out, err := exec.Command("git", "-C", mirrorDir, "for-each-ref", "--format=%(objectname) %(refname)").Output()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list refs of %s: %v", mirrorDir, err)
+	}
+
+	refs := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if hash, ref, ok := strings.Cut(line, " "); ok {
+			refs[ref] = hash
+		}
+	}
+
+	head, err := exec.Command("git", "-C", mirrorDir, "symbolic-ref", "-q", "HEAD").Output()
+	if err != nil {
+		return refs, "", nil
+	}
+	return refs, strings.TrimSpace(string(head)), nil
+}
+
+// ArchiveRepo tars <owner>/<repo> into <owner>/<repo>.tar.gz; entries inside the archive stay rooted at <repo>/
+func ArchiveRepo(repoPath string) error {
+	parentDir := path.Dir(repoPath)
+	repoDir := path.Base(repoPath)
+	archiveName := path.Base(ArchiveFileName(repoPath))
 
 	return retryCommand(func() *exec.Cmd {
 		return exec.Command(
 			"sh",
 			"-c",
 			fmt.Sprintf(
-				"cd _Repos && tar -czf '%s' '%s' && rm -rf '%s'",
+				"cd '_Repos/%s' && tar -czf '%s' '%s' && rm -rf '%s'",
+				parentDir,
 				archiveName,
 				repoDir,
 				repoDir,
 			),
 		)
-	}, fmt.Sprintf("Archive %s", repoName), cloneTimeout)
+	}, fmt.Sprintf("Archive %s", repoPath), cloneTimeout)
+}
+
+// ArchiveDirectory tars dir into archive inside _Repos; entries inside the archive stay rooted at the
+// directory's base name
+func ArchiveDirectory(dir string, archive string) error {
+	archiveCmd := exec.Command("sh", "-c",
+		fmt.Sprintf("mkdir -p \"$(dirname '_Repos/%s')\" && tar -czf '_Repos/%s' -C '%s' '%s'",
+			archive, archive, path.Dir(dir), path.Base(dir)))
+	if out, err := archiveCmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to archive %s: %v: %s", dir, err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
+// ExtractArchive unpacks an archive inside _Repos into dest
+func ExtractArchive(archive string, dest string) error {
+	extractCmd := exec.Command("sh", "-c",
+		fmt.Sprintf("mkdir -p '%s' && tar -xzf '_Repos/%s' -C '%s'", dest, archive, dest))
+	if out, err := extractCmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to extract %s: %v: %s", archive, err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
+// StageFiles stages files inside _Repos without committing them
+func StageFiles(paths ...string) error {
+	quoted := make([]string, 0, len(paths))
+	for _, path := range paths {
+		quoted = append(quoted, fmt.Sprintf("'%s'", path))
+	}
+
+	stageCmd := exec.Command("sh", "-c",
+		fmt.Sprintf("cd _Repos && git add %s", strings.Join(quoted, " ")))
+	if out, err := stageCmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to stage %v: %v: %s", paths, err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
 }
 
-func StageAndCommitRepo(repoName string, commitMsg string) {
+func StageAndCommitRepo(paths []string, commitMsg string) {
+	quoted := make([]string, 0, len(paths))
+	for _, path := range paths {
+		quoted = append(quoted, fmt.Sprintf("'%s'", path))
+	}
+
 	commitCmd := exec.Command("sh", "-c",
-		fmt.Sprintf("cd _Repos && git add '%s' && "+
+		fmt.Sprintf("cd _Repos && git add %s && "+
 			"if git diff --staged --quiet; then "+
 			"  echo 'no changes'; "+
 			"else "+
 			"  git commit -m '%s' -s; "+
-			"fi", repoName, commitMsg))
+			"fi", strings.Join(quoted, " "), commitMsg))
 
 	if _, err := commitCmd.CombinedOutput(); err != nil {
 		util.Logger().Warn("Commit failed",
-			zap.String("repository", repoName),
+			zap.Strings("paths", paths),
 			zap.Error(err),
 		)
 	}
 }
 
+// MoveTrackedFile renames a file inside _Repos, keeping git history when the file is tracked.
+// It reports false when there was nothing to move.
+func MoveTrackedFile(from string, to string) (bool, error) {
+	if _, err := os.Stat(fmt.Sprintf("_Repos/%s", from)); err != nil {
+		return false, nil
+	}
+
+	moveCmd := exec.Command("sh", "-c",
+		fmt.Sprintf("cd _Repos && mkdir -p \"$(dirname '%s')\" && (git mv -f '%s' '%s' 2>/dev/null || mv -f '%s' '%s')",
+			to, from, to, from, to))
+	if out, err := moveCmd.CombinedOutput(); err != nil {
+		return false, fmt.Errorf("failed to move %s to %s: %v: %s", from, to, err, strings.TrimSpace(string(out)))
+	}
+
+	return true, nil
+}
+
+// RemoveTrackedFiles stages the removal of files and directories inside _Repos, ignoring ones that do not exist
+func RemoveTrackedFiles(paths ...string) error {
+	quoted := make([]string, 0, len(paths))
+	for _, path := range paths {
+		quoted = append(quoted, fmt.Sprintf("'%s'", path))
+	}
+
+	removeCmd := exec.Command("sh", "-c",
+		fmt.Sprintf("cd _Repos && git rm -r -f --ignore-unmatch %s >/dev/null && rm -rf %s",
+			strings.Join(quoted, " "), strings.Join(quoted, " ")))
+	if out, err := removeCmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to remove %v: %v: %s", paths, err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
+// HasStagedChanges reports whether anything is staged in _Repos
+func HasStagedChanges() bool {
+	return exec.Command("git", "-C", "_Repos", "diff", "--staged", "--quiet").Run() != nil
+}
+
+// CommitStaged commits whatever is staged in _Repos and reports whether a commit was made
+func CommitStaged(commitMsg string) (bool, error) {
+	if !HasStagedChanges() {
+		return false, nil
+	}
+
+	commitCmd := exec.Command("sh", "-c",
+		fmt.Sprintf("cd _Repos && git commit -m '%s' -s", commitMsg))
+	if out, err := commitCmd.CombinedOutput(); err != nil {
+		return false, fmt.Errorf("commit failed: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	return true, nil
+}
+
 func PushBackupRepo(label string) error {
 	return retryCommand(func() *exec.Cmd {
 		cmd := exec.Command(
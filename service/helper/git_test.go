@@ -0,0 +1,104 @@
+package helper
+
+import (
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestRefsFingerprint(t *testing.T) {
+	base := map[string]string{
+		"HEAD":               "1111111111111111111111111111111111111111",
+		"refs/heads/main":    "1111111111111111111111111111111111111111",
+		"refs/tags/v1.0.0":   "2222222222222222222222222222222222222222",
+		"refs/heads/feature": "3333333333333333333333333333333333333333",
+	}
+	fingerprint := RefsFingerprint(base)
+	if len(fingerprint) != 64 {
+		t.Fatalf("fingerprint %q is not a hex SHA-256", fingerprint)
+	}
+
+	copied := make(map[string]string, len(base))
+	for ref, hash := range base {
+		copied[ref] = hash
+	}
+	if RefsFingerprint(copied) != fingerprint {
+		t.Error("fingerprint depends on map iteration order")
+	}
+
+	tests := []struct {
+		name   string
+		change func(map[string]string)
+	}{
+		{name: "tag created", change: func(refs map[string]string) { refs["refs/tags/v1.1.0"] = "4444444444444444444444444444444444444444" }},
+		{name: "branch moved", change: func(refs map[string]string) { refs["refs/heads/feature"] = "5555555555555555555555555555555555555555" }},
+		{name: "tag deleted", change: func(refs map[string]string) { delete(refs, "refs/tags/v1.0.0") }},
+		{name: "ref renamed", change: func(refs map[string]string) {
+			refs["refs/heads/feature-2"] = refs["refs/heads/feature"]
+			delete(refs, "refs/heads/feature")
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := make(map[string]string, len(base))
+			for ref, hash := range base {
+				changed[ref] = hash
+			}
+			tt.change(changed)
+			if RefsFingerprint(changed) == fingerprint {
+				t.Error("fingerprint unchanged")
+			}
+		})
+	}
+}
+
+func TestGetRemoteRefs(t *testing.T) {
+	dir := t.TempDir()
+	remote := filepath.Join(dir, "remote.git")
+	work := filepath.Join(dir, "work")
+
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Env = append(cmd.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+		)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+
+	git("init", "-q", "--bare", "-b", "main", remote)
+	if _, err := GetRemoteRefs("file://"+remote, nil); !errors.Is(err, ErrRemoteMissing) {
+		t.Fatalf("empty remote: err = %v, want ErrRemoteMissing", err)
+	}
+
+	git("init", "-q", "-b", "main", work)
+	git("-C", work, "commit", "-q", "--allow-empty", "-m", "initial")
+	git("-C", work, "tag", "-a", "-m", "release", "v1.0.0")
+	git("-C", work, "push", "-q", remote, "main", "v1.0.0", "HEAD:refs/pull/1/head")
+
+	refs, err := GetRemoteRefs("file://"+remote, nil)
+	if err != nil {
+		t.Fatalf("GetRemoteRefs: %v", err)
+	}
+
+	want := []string{"HEAD", "refs/heads/main", "refs/tags/v1.0.0"}
+	if len(refs) != len(want) {
+		t.Fatalf("refs = %v, want exactly %v", refs, want)
+	}
+	for _, ref := range want {
+		if refs[ref] == "" {
+			t.Errorf("ref %s missing from %v", ref, refs)
+		}
+	}
+	if refs["HEAD"] != refs["refs/heads/main"] {
+		t.Errorf("HEAD %s does not match main %s", refs["HEAD"], refs["refs/heads/main"])
+	}
+	// The annotated tag is listed by its tag object, not the peeled commit
+	if refs["refs/tags/v1.0.0"] == refs["HEAD"] {
+		t.Error("annotated tag resolved to its peeled commit")
+	}
+}
@@ -0,0 +1,98 @@
+package helper
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// sshGreetings are the messages forges print on a successful `ssh -T`, which still exits non-zero
+var sshGreetings = []string{"successfully authenticated", "Welcome to GitLab", "Hi there", "Hi "}
+
+// SSHTarget turns a clone host into ssh arguments. ok is false for hosts that are not cloned over SSH.
+func SSHTarget(cloneHost string) (args []string, ok bool) {
+	if !strings.Contains(cloneHost, "://") {
+		return []string{cloneHost}, cloneHost != ""
+	}
+
+	parsed, err := url.Parse(cloneHost)
+	if err != nil || parsed.Scheme != "ssh" {
+		return nil, false
+	}
+
+	host := parsed.Hostname()
+	if parsed.User != nil {
+		host = parsed.User.Username() + "@" + host
+	}
+	if port := parsed.Port(); port != "" {
+		return []string{"-p", port, host}, true
+	}
+	return []string{host}, true
+}
+
+// CheckSSHHost authenticates against an SSH clone host without running a command
+func CheckSSHHost(target []string) (string, error) {
+	args := append([]string{"-T", "-o", "BatchMode=yes", "-o", "ConnectTimeout=10", "-o", "StrictHostKeyChecking=accept-new"}, target...)
+	out, err := exec.Command("ssh", args...).CombinedOutput()
+	output := strings.TrimSpace(string(out))
+
+	for _, greeting := range sshGreetings {
+		if strings.Contains(output, greeting) {
+			return firstLine(output), nil
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("%v: %s", err, firstLine(output))
+	}
+	return firstLine(output), nil
+}
+
+// DryRunPush proves push access to a remote by dry-run pushing a throwaway commit from a temp repo,
+// so the check works before _Repos exists and never touches it
+func DryRunPush(remote string) error {
+	dir, err := os.MkdirTemp("", "preflight-push-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	script := fmt.Sprintf(`cd '%s' && \
+		git init -q && \
+		git -c user.email=preflight@localhost -c user.name=preflight commit -q --allow-empty -m preflight && \
+		git push --dry-run '%s' HEAD:refs/heads/preflight-check`, dir, remote)
+	cmd := exec.Command("sh", "-c", script)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_SSH_COMMAND=ssh -o BatchMode=yes -o ConnectTimeout=10")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %s", err, gitErrorLine(string(out)))
+	}
+
+	return nil
+}
+
+// FreeDiskBytes reports the space available to the worker on the filesystem holding dir
+func FreeDiskBytes(dir string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, err
+	}
+	return stat.Bavail * uint64(stat.Bsize), nil
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
+
+// gitErrorLine picks the fatal/error line out of git's output, which is followed by generic hints
+func gitErrorLine(output string) string {
+	output = strings.TrimSpace(output)
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, "fatal:") || strings.HasPrefix(line, "error:") {
+			return line
+		}
+	}
+	return firstLine(output)
+}
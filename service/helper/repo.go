@@ -1,17 +1,49 @@
 package helper
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/url"
+	"os"
+	"path"
 	"strings"
 	"time"
+
+	"github.com/MishraShardendu22/github-backup/model"
 )
 
 func ExtractRepoName(fullName string) string {
 	return fullName[strings.Index(fullName, "/")+1:]
 }
 
-func BuildCloneURL(fullName string) string {
-	return fmt.Sprintf("git@github.com-project:%s.git", fullName)
+// RepoPath is where a repo lives inside _Repos: <owner>/<repo>, so equal names under different owners never collide
+func RepoPath(fullName string) string {
+	return path.Clean(fullName)
+}
+
+// BuildCloneURL joins a clone host and a repo's full name. Hosts with a scheme (https://, ssh://, file://)
+// are treated as URL prefixes; anything else is an scp-style SSH host such as git@github.com-project.
+func BuildCloneURL(cloneHost string, fullName string) string {
+	if strings.Contains(cloneHost, "://") {
+		return fmt.Sprintf("%s/%s.git", strings.TrimRight(cloneHost, "/"), fullName)
+	}
+	return fmt.Sprintf("%s:%s.git", cloneHost, fullName)
+}
+
+// CloneURLHost returns the lower-cased host of a clone URL or clone host in either form BuildCloneURL accepts,
+// so git@github.com:acme/api.git and https://github.com/acme/api.git both give github.com
+func CloneURLHost(cloneURL string) string {
+	if strings.Contains(cloneURL, "://") {
+		if parsed, err := url.Parse(cloneURL); err == nil {
+			return strings.ToLower(parsed.Hostname())
+		}
+		return ""
+	}
+	host, _, _ := strings.Cut(cloneURL, ":")
+	if _, afterUser, ok := strings.Cut(host, "@"); ok {
+		host = afterUser
+	}
+	return strings.ToLower(host)
 }
 
 func BuildCommitMessage(repoName string) string {
@@ -19,3 +51,100 @@ func BuildCommitMessage(repoName string) string {
 		time.Now().Format("2006-01-02 Monday 15:04:05"),
 		repoName))
 }
+
+// GistsPrefix is the directory in _Repos holding gist archives as gists/<owner>/<id>.tar.gz
+const GistsPrefix = "gists"
+
+func GistFullName(owner string, id string) string {
+	return fmt.Sprintf("%s/%s/%s", GistsPrefix, owner, id)
+}
+
+// ParseGistFullName splits gists/<owner>/<id> back into its owner and gist ID
+func ParseGistFullName(fullName string) (owner string, id string, ok bool) {
+	parts := strings.Split(fullName, "/")
+	if len(parts) != 3 || parts[0] != GistsPrefix {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
+
+// GovernancePrefix is the directory in _Repos holding the per-org governance snapshots
+const GovernancePrefix = "_governance"
+
+func GovernancePath(org string) string {
+	return fmt.Sprintf("%s/%s", GovernancePrefix, org)
+}
+
+// MigrationsPrefix is the directory in _Repos holding organization migration archives as <org>/archive-NNN.tar.gz
+const MigrationsPrefix = "_migrations"
+
+func MigrationsPath(org string) string {
+	return fmt.Sprintf("%s/%s", MigrationsPrefix, org)
+}
+
+// DeletedPrefix is the directory in _Repos holding archives of repos that disappeared upstream
+const DeletedPrefix = "_deleted"
+
+func ArchiveFileName(repoPath string) string {
+	return fmt.Sprintf("%s.tar.gz", repoPath)
+}
+
+func DeletedPath(path string) string {
+	return fmt.Sprintf("%s/%s", DeletedPrefix, path)
+}
+
+func MetadataFileName(repoPath string) string {
+	return fmt.Sprintf("%s.metadata.json", repoPath)
+}
+
+// MetadataPathForArchive returns the metadata file that sits next to an archive path
+func MetadataPathForArchive(archivePath string) string {
+	return MetadataFileName(strings.TrimSuffix(archivePath, ".tar.gz"))
+}
+
+// MetaArchiveFileName is the sibling archive holding the repo's exported issues, pull requests and comments
+func MetaArchiveFileName(repoPath string) string {
+	return fmt.Sprintf("%s.meta.tar.gz", repoPath)
+}
+
+// ReleasesDirName is the sibling directory holding releases.json and the downloaded release assets
+func ReleasesDirName(repoPath string) string {
+	return fmt.Sprintf("%s.releases", repoPath)
+}
+
+// MirrorDirName is the sibling directory holding the git bundle of the repo's full history and its bundle.json
+func MirrorDirName(repoPath string) string {
+	return fmt.Sprintf("%s.mirror", repoPath)
+}
+
+// ArchiveSidecars lists the files and directories that travel with a repo archive when it is renamed,
+// tombstoned, restored or purged
+func ArchiveSidecars(repoPath string) []string {
+	return []string{MetadataFileName(repoPath), MetaArchiveFileName(repoPath), ReleasesDirName(repoPath), MirrorDirName(repoPath)}
+}
+
+// SidecarsForArchive is ArchiveSidecars for an archive path such as a tombstone's _deleted/<owner>/<repo>.tar.gz
+func SidecarsForArchive(archivePath string) []string {
+	return ArchiveSidecars(strings.TrimSuffix(archivePath, ".tar.gz"))
+}
+
+// WriteRepoMetadata stores the discovered repository metadata next to its archive in _Repos
+func WriteRepoMetadata(repo model.Repo, repoPath string, commitHash string) error {
+	doc := model.RepoMetadataFile{
+		BackedUpAt: time.Now().UTC(),
+		CommitHash: commitHash,
+		Repository: repo,
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata for %s: %v", repo.FullName, err)
+	}
+
+	metadataPath := fmt.Sprintf("_Repos/%s", MetadataFileName(repoPath))
+	if err := os.WriteFile(metadataPath, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write metadata for %s: %v", repo.FullName, err)
+	}
+
+	return nil
+}
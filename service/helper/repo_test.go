@@ -0,0 +1,20 @@
+package helper
+
+import "testing"
+
+func TestCloneURLHost(t *testing.T) {
+	tests := map[string]string{
+		"git@github.com:acme/api.git":                     "github.com",
+		"git@github.com-project:acme/api.git":             "github.com-project",
+		"git@GitHub.com":                                  "github.com",
+		"https://github.com/acme/api.git":                 "github.com",
+		"https://x-access-token@ghe.example.com/acme/api": "ghe.example.com",
+		"ssh://git@ghe.example.com:2222/acme/api.git":     "ghe.example.com",
+		"ssh://git@ghe.example.com:2222":                  "ghe.example.com",
+	}
+	for cloneURL, want := range tests {
+		if got := CloneURLHost(cloneURL); got != want {
+			t.Errorf("CloneURLHost(%s) = %s, want %s", cloneURL, got, want)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/MishraShardendu22/github-backup/database"
+	"github.com/MishraShardendu22/github-backup/service/helper"
+	"github.com/MishraShardendu22/github-backup/service/monitor"
+	"github.com/MishraShardendu22/github-backup/util"
+	"go.uber.org/zap"
+)
+
+const ownerLayoutMigration = "owner_qualified_archive_layout"
+
+// flatArchive is a tracked repo or tombstone whose archive may still sit at the old flat path
+type flatArchive struct {
+	fullName  string
+	tombstone bool
+	from      string
+	to        string
+}
+
+// migrateFlatArchives moves archives from the old flat _Repos/<repo>.tar.gz layout to
+// _Repos/<owner>/<repo>.tar.gz. It runs once per database. Flat archives shared by several
+// owners cannot be attributed, so those repos are re-backed up instead of guessed at.
+func migrateFlatArchives(db *sql.DB) {
+	if db == nil {
+		return
+	}
+
+	applied, err := database.IsMigrationApplied(db, ownerLayoutMigration)
+	if err != nil {
+		util.Logger().Warn("Failed to check archive layout migration", zap.Error(err))
+		return
+	}
+	if applied {
+		return
+	}
+
+	candidates, err := flatArchiveCandidates(db)
+	if err != nil {
+		util.Logger().Warn("Failed to list archives for layout migration", zap.Error(err))
+		return
+	}
+
+	byFlatPath := make(map[string][]flatArchive)
+	for _, candidate := range candidates {
+		byFlatPath[candidate.from] = append(byFlatPath[candidate.from], candidate)
+	}
+
+	moved := 0
+	failed := 0
+	for flatPath, owners := range byFlatPath {
+		if len(owners) > 1 {
+			resetCollidingArchive(db, flatPath, owners)
+			continue
+		}
+
+		candidate := owners[0]
+		ok, err := helper.MoveTrackedFile(candidate.from, candidate.to)
+		if err != nil {
+			failed++
+			util.Logger().Warn("Failed to migrate archive to owner-qualified layout",
+				zap.String("repository", candidate.fullName),
+				zap.Error(err),
+			)
+			continue
+		}
+		if _, err := helper.MoveTrackedFile(helper.MetadataPathForArchive(candidate.from), helper.MetadataPathForArchive(candidate.to)); err != nil {
+			util.Logger().Warn("Failed to migrate metadata to owner-qualified layout",
+				zap.String("repository", candidate.fullName),
+				zap.Error(err),
+			)
+		}
+
+		if candidate.tombstone {
+			if err := database.UpdateTombstoneArchivePath(db, candidate.fullName, candidate.to); err != nil {
+				util.Logger().Warn("Failed to update tombstone archive path",
+					zap.String("repository", candidate.fullName),
+					zap.Error(err),
+				)
+			}
+		}
+
+		if ok {
+			moved++
+		}
+	}
+
+	commitMsg := helper.SanitizeCommitMessage(fmt.Sprintf("Migrated %d archive(s) to owner-qualified layout on %s",
+		moved, time.Now().Format("2006-01-02 Monday 15:04:05")))
+	pushIfCommitted(commitMsg, "archive-layout-migration")
+
+	if failed > 0 {
+		util.Logger().Warn("Archive layout migration incomplete; will retry next run",
+			zap.Int("failed", failed),
+		)
+		return
+	}
+
+	if err := database.MarkMigrationApplied(db, ownerLayoutMigration); err != nil {
+		util.Logger().Warn("Failed to record archive layout migration", zap.Error(err))
+	}
+
+	util.Logger().Info("Archive layout migration complete",
+		zap.Int("moved", moved),
+	)
+	if mon := monitor.Get(); mon != nil && moved > 0 {
+		mon.Log("info", fmt.Sprintf("Migrated %d archives to <owner>/<repo>.tar.gz layout", moved), "")
+	}
+}
+
+func flatArchiveCandidates(db *sql.DB) ([]flatArchive, error) {
+	dbRepos, err := database.GetAllReposFromDB(db)
+	if err != nil {
+		return nil, err
+	}
+
+	tombstones, err := database.GetAllTombstones(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []flatArchive
+	for _, repo := range dbRepos {
+		candidates = append(candidates, flatArchive{
+			fullName: repo.FullName,
+			from:     helper.ArchiveFileName(helper.ExtractRepoName(repo.FullName)),
+			to:       helper.ArchiveFileName(helper.RepoPath(repo.FullName)),
+		})
+	}
+
+	for _, tombstone := range tombstones {
+		flat := helper.DeletedPath(helper.ArchiveFileName(helper.ExtractRepoName(tombstone.FullName)))
+		if tombstone.ArchivePath != flat {
+			continue
+		}
+		candidates = append(candidates, flatArchive{
+			fullName:  tombstone.FullName,
+			tombstone: true,
+			from:      flat,
+			to:        helper.DeletedPath(helper.ArchiveFileName(helper.RepoPath(tombstone.FullName))),
+		})
+	}
+
+	return candidates, nil
+}
+
+// resetCollidingArchive handles org/api and user/api having overwritten each other in _Repos/api.tar.gz:
+// the file is dropped and each live repo's hash is cleared so both are cloned into their own paths
+func resetCollidingArchive(db *sql.DB, flatPath string, owners []flatArchive) {
+	names := make([]string, 0, len(owners))
+	for _, owner := range owners {
+		names = append(names, owner.fullName)
+		if owner.tombstone {
+			continue
+		}
+		if err := database.ResetRepoHash(db, owner.fullName); err != nil {
+			util.Logger().Warn("Failed to reset hash of colliding repository",
+				zap.String("repository", owner.fullName),
+				zap.Error(err),
+			)
+		}
+	}
+
+	if err := helper.RemoveTrackedFiles(flatPath, helper.MetadataPathForArchive(flatPath)); err != nil {
+		util.Logger().Warn("Failed to remove colliding flat archive",
+			zap.String("archive", flatPath),
+			zap.Error(err),
+		)
+	}
+
+	util.Logger().Warn("Flat archive was shared by several repositories; they will be backed up again",
+		zap.String("archive", flatPath),
+		zap.Strings("repositories", names),
+	)
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/MishraShardendu22/github-backup/controller"
+	"github.com/MishraShardendu22/github-backup/database"
+	"github.com/MishraShardendu22/github-backup/model"
+	"github.com/MishraShardendu22/github-backup/service/helper"
+	"github.com/MishraShardendu22/github-backup/service/monitor"
+	"github.com/MishraShardendu22/github-backup/util"
+	"go.uber.org/zap"
+)
+
+// migrationBatchSize keeps each migration small enough for GitHub to export in reasonable time
+const migrationBatchSize = 100
+
+// RunMigrationArchives is the migration-archive mode: for every GitHub org source it asks GitHub for
+// organization migration archives of the selected repos, waits for them to be exported, and stores them
+// under _Repos/_migrations/<org>/archive-NNN.tar.gz next to a JSON record. Each archive is logged to the
+// monitor as a backup result. It returns an error when any archive could not be produced.
+func RunMigrationArchives(cfg *model.ConfigModel, db *sql.DB) error {
+	if err := database.InitSchema(db); err != nil {
+		return err
+	}
+	controller.InitGitHubClient(db)
+
+	var sources []model.Source
+	for _, source := range cfg.Sources {
+		if source.Provider == model.ProviderGitHub && source.Kind == model.SourceKindOrg {
+			sources = append(sources, source)
+		}
+	}
+	if len(sources) == 0 {
+		return fmt.Errorf("migration archives need at least one GitHub org source")
+	}
+
+	if err := helper.EnsureReposDirExists(); err != nil {
+		return err
+	}
+	if err := helper.EnsureBackupRepoInitialized(cfg); err != nil {
+		return err
+	}
+
+	type migrationJob struct {
+		source model.Source
+		batch  int
+		repos  []string
+	}
+
+	var jobs []migrationJob
+	var failedSources []string
+	for _, sd := range GetAllRepos(sources) {
+		if sd.Err != nil {
+			failedSources = append(failedSources, sd.Source.Name)
+			continue
+		}
+
+		repos, _, err := filterRepos(sd.Repos, cfg.Filters)
+		if err != nil {
+			return err
+		}
+		var names []string
+		for _, repo := range repos {
+			names = append(names, sd.Source.RemoteName(repo.FullName))
+		}
+
+		for start, batch := 0, 1; start < len(names); start, batch = start+migrationBatchSize, batch+1 {
+			end := start + migrationBatchSize
+			if end > len(names) {
+				end = len(names)
+			}
+			jobs = append(jobs, migrationJob{source: sd.Source, batch: batch, repos: names[start:end]})
+		}
+	}
+
+	mon := monitor.Get()
+	start := time.Now()
+	if mon != nil {
+		mon.StartRun(len(jobs))
+		mon.Log("info", fmt.Sprintf("Starting %d organization migration archive(s)", len(jobs)), "")
+		if len(failedSources) > 0 {
+			reason := fmt.Sprintf("Discovery incomplete; failed sources: %s", strings.Join(failedSources, ", "))
+			mon.MarkPartial(reason)
+			mon.Log("warn", reason, "")
+		}
+	}
+
+	successCount := 0
+	var failed []string
+	for _, job := range jobs {
+		archive := path.Join(helper.MigrationsPath(job.source.Account), fmt.Sprintf("archive-%03d", job.batch))
+		jobStart := time.Now()
+
+		record, err := produceMigrationArchive(job.source, job.repos, archive, cfg)
+		durationMs := time.Since(jobStart).Milliseconds()
+		if err != nil {
+			failed = append(failed, archive)
+			util.Logger().Error("Migration archive failed",
+				zap.String("org", job.source.Account),
+				zap.String("archive", archive),
+				zap.Error(err),
+			)
+			if mon != nil {
+				mon.LogRepoResult(archive, "failed", "", nil, 0, durationMs, err.Error())
+				mon.Log("error", "Migration archive failed: "+err.Error(), archive)
+				mon.UpdateProgress(successCount, len(failed), 0)
+			}
+			continue
+		}
+
+		commitMsg := helper.SanitizeCommitMessage(fmt.Sprintf("Migration archive %d on %s for the org %s",
+			record.ID, time.Now().Format("2006-01-02 Monday 15:04:05"), job.source.Account))
+		pushIfCommitted(commitMsg, "migration "+archive)
+
+		successCount++
+		util.Logger().Info("✓ Migration archive stored",
+			zap.String("org", job.source.Account),
+			zap.String("archive", archive),
+			zap.Int64("migration_id", record.ID),
+			zap.Int("repositories", len(record.Repositories)),
+			zap.Int64("size_bytes", record.SizeBytes),
+		)
+		if mon != nil {
+			mon.LogRepoResult(archive, "completed", record.GUID, nil, record.SizeBytes, durationMs, "")
+			mon.Log("info", fmt.Sprintf("Migration %d archived %d repositories", record.ID, len(record.Repositories)), archive)
+			mon.UpdateProgress(successCount, len(failed), 0)
+		}
+	}
+
+	errMsg := ""
+	if len(failed) > 0 {
+		errMsg = fmt.Sprintf("%d migration archive(s) failed", len(failed))
+	} else if len(failedSources) > 0 {
+		errMsg = fmt.Sprintf("discovery failed for: %s", strings.Join(failedSources, ", "))
+	}
+	if mon != nil {
+		mon.CompleteRun(successCount, len(failed), 0, time.Since(start).Milliseconds(), errMsg)
+	}
+
+	if errMsg != "" {
+		return fmt.Errorf("%s", errMsg)
+	}
+	return nil
+}
+
+// produceMigrationArchive starts one migration, polls it until GitHub has exported it, downloads the
+// archive and stages it together with its record
+func produceMigrationArchive(source model.Source, repos []string, archive string, cfg *model.ConfigModel) (model.MigrationRecord, error) {
+	record := model.MigrationRecord{Org: source.Account, Repositories: repos}
+
+	token, err := controller.SourceToken(source)
+	if err != nil {
+		return record, fmt.Errorf("token for source %s: %w", source.Name, err)
+	}
+	apiURL := strings.TrimRight(source.APIURL, "/")
+
+	migration, err := controller.StartMigration(apiURL, source.Account, token, repos)
+	if err != nil {
+		return record, err
+	}
+	record.ID = migration.ID
+	record.GUID = migration.GUID
+	record.StartedAt = time.Now().UTC().Format(time.RFC3339)
+
+	util.Logger().Info("Migration started",
+		zap.String("org", source.Account),
+		zap.Int64("migration_id", migration.ID),
+		zap.Int("repositories", len(repos)),
+	)
+
+	deadline := time.Now().Add(time.Duration(cfg.MigrationTimeout) * time.Minute)
+	for migration.State != model.MigrationStateExported {
+		if migration.State == model.MigrationStateFailed {
+			return record, fmt.Errorf("migration %d failed on GitHub", migration.ID)
+		}
+		if time.Now().After(deadline) {
+			return record, fmt.Errorf("migration %d still %q after %d minutes", migration.ID, migration.State, cfg.MigrationTimeout)
+		}
+
+		time.Sleep(time.Duration(cfg.MigrationPollSeconds) * time.Second)
+
+		// A token refreshed mid-wait keeps long exports from failing on an expired installation token
+		if token, err = controller.SourceToken(source); err != nil {
+			return record, fmt.Errorf("token for source %s: %w", source.Name, err)
+		}
+		if migration, err = controller.MigrationStatus(apiURL, source.Account, token, record.ID); err != nil {
+			return record, fmt.Errorf("poll migration %d: %w", record.ID, err)
+		}
+		util.Logger().Info("Migration state",
+			zap.String("org", source.Account),
+			zap.Int64("migration_id", record.ID),
+			zap.String("state", migration.State),
+		)
+	}
+	record.ExportedAt = time.Now().UTC().Format(time.RFC3339)
+
+	localArchive := "_Repos/" + helper.ArchiveFileName(archive)
+	if err := os.MkdirAll(path.Dir(localArchive), 0o755); err != nil {
+		return record, err
+	}
+	stale, err := filepath.Glob(localArchive + ".part-*")
+	if err != nil {
+		return record, err
+	}
+	for _, part := range stale {
+		os.Remove(part)
+	}
+
+	size, err := controller.DownloadMigrationArchive(apiURL, source.Account, token, record.ID, localArchive)
+	if err != nil {
+		return record, fmt.Errorf("download migration %d: %w", record.ID, err)
+	}
+	record.SizeBytes = size
+	record.Archive = path.Base(localArchive)
+
+	if record.SHA256, err = helper.FileSHA256(localArchive); err != nil {
+		return record, err
+	}
+
+	if size > maxGitHubBlobSize {
+		parts, err := helper.SplitFile(localArchive, maxGitHubBlobSize)
+		if err != nil {
+			return record, err
+		}
+		for _, part := range parts {
+			record.Parts = append(record.Parts, path.Base(part))
+		}
+	}
+
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return record, err
+	}
+	if err := os.WriteFile("_Repos/"+archive+".json", append(data, '\n'), 0o644); err != nil {
+		return record, err
+	}
+
+	return record, helper.StageFiles(path.Dir(archive))
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/MishraShardendu22/github-backup/model"
+)
+
+// fakeMigrations serves the organization migration endpoints: the migration reports pending and
+// exporting before it is exported, and the archive download redirects to a storage URL
+func fakeMigrations(t *testing.T, archive []byte) *httptest.Server {
+	t.Helper()
+
+	var polls int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /orgs/acme/migrations", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			t.Errorf("start migration: Authorization = %q", r.Header.Get("Authorization"))
+		}
+		var body struct {
+			Repositories     []string `json:"repositories"`
+			LockRepositories bool     `json:"lock_repositories"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("start migration: decode body: %v", err)
+		}
+		if strings.Join(body.Repositories, ",") != "acme/api,acme/web" || body.LockRepositories {
+			t.Errorf("start migration: body = %+v", body)
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":7,"guid":"guid-7","state":"pending"}`))
+	})
+	mux.HandleFunc("GET /orgs/acme/migrations/7", func(w http.ResponseWriter, r *http.Request) {
+		states := []string{model.MigrationStateExporting, model.MigrationStateExporting, model.MigrationStateExported}
+		poll := int(atomic.AddInt32(&polls, 1)) - 1
+		if poll >= len(states) {
+			poll = len(states) - 1
+		}
+		json.NewEncoder(w).Encode(model.Migration{ID: 7, GUID: "guid-7", State: states[poll]})
+	})
+	mux.HandleFunc("GET /orgs/acme/migrations/7/archive", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/storage/migration-7.tar.gz", http.StatusFound)
+	})
+	mux.HandleFunc("GET /storage/migration-7.tar.gz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(archive)
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestProduceMigrationArchive(t *testing.T) {
+	archive := []byte("fake migration archive contents")
+	srv := fakeMigrations(t, archive)
+
+	t.Chdir(t.TempDir())
+	if out, err := exec.Command("git", "init", "-q", "_Repos").CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+
+	source := model.Source{Name: "acme", Account: "acme", Token: "test-token", APIURL: srv.URL + "/"}
+	cfg := &model.ConfigModel{MigrationPollSeconds: 0, MigrationTimeout: 1}
+
+	record, err := produceMigrationArchive(source, []string{"acme/api", "acme/web"}, "_migrations/acme/archive-001", cfg)
+	if err != nil {
+		t.Fatalf("produceMigrationArchive: %v", err)
+	}
+
+	sum := sha256.Sum256(archive)
+	if record.ID != 7 || record.GUID != "guid-7" || record.SizeBytes != int64(len(archive)) ||
+		record.SHA256 != hex.EncodeToString(sum[:]) || record.Archive != "archive-001.tar.gz" {
+		t.Errorf("record = %+v", record)
+	}
+	if record.StartedAt == "" || record.ExportedAt == "" {
+		t.Errorf("record timestamps missing: %+v", record)
+	}
+
+	downloaded, err := os.ReadFile("_Repos/_migrations/acme/archive-001.tar.gz")
+	if err != nil || string(downloaded) != string(archive) {
+		t.Fatalf("downloaded archive = %q, %v", downloaded, err)
+	}
+
+	var written model.MigrationRecord
+	data, err := os.ReadFile("_Repos/_migrations/acme/archive-001.json")
+	if err != nil {
+		t.Fatalf("read record: %v", err)
+	}
+	if err := json.Unmarshal(data, &written); err != nil || written.SHA256 != record.SHA256 {
+		t.Errorf("written record = %+v, %v", written, err)
+	}
+
+	out, err := exec.Command("git", "-C", "_Repos", "diff", "--cached", "--name-only").Output()
+	if err != nil {
+		t.Fatalf("git diff: %v", err)
+	}
+	staged := strings.Fields(string(out))
+	if strings.Join(staged, " ") != "_migrations/acme/archive-001.json _migrations/acme/archive-001.tar.gz" {
+		t.Errorf("staged = %v", staged)
+	}
+}
+
+func TestProduceMigrationArchiveFailedExport(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /orgs/acme/migrations", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":8,"guid":"guid-8","state":"pending"}`))
+	})
+	mux.HandleFunc("GET /orgs/acme/migrations/8", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":8,"guid":"guid-8","state":"failed"}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	t.Chdir(t.TempDir())
+
+	source := model.Source{Name: "acme", Account: "acme", Token: "test-token", APIURL: srv.URL}
+	cfg := &model.ConfigModel{MigrationPollSeconds: 0, MigrationTimeout: 1}
+
+	_, err := produceMigrationArchive(source, []string{"acme/api"}, "_migrations/acme/archive-001", cfg)
+	if err == nil || !strings.Contains(err.Error(), "failed on GitHub") {
+		t.Fatalf("err = %v, want a failed migration", err)
+	}
+	if _, statErr := os.Stat("_Repos/_migrations/acme/archive-001.tar.gz"); statErr == nil {
+		t.Error("archive downloaded for a failed migration")
+	}
+}
@@ -0,0 +1,238 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/MishraShardendu22/github-backup/model"
+	"github.com/MishraShardendu22/github-backup/service/helper"
+)
+
+const (
+	mirrorBundleName   = "full.bundle"
+	mirrorManifestName = "bundle.json"
+)
+
+// backupModes resolves the backup mode of each repo: the first matching BACKUP_MODE_RULES entry wins,
+// then the mode of the source that discovered the repo, then BACKUP_MODE
+type backupModes struct {
+	config   *model.ConfigModel
+	patterns []namePattern
+}
+
+func newBackupModes(config *model.ConfigModel) (*backupModes, error) {
+	raw := make([]string, 0, len(config.BackupModeRules))
+	for _, rule := range config.BackupModeRules {
+		raw = append(raw, rule.Pattern)
+	}
+	patterns, err := compileNamePatterns(raw)
+	if err != nil {
+		return nil, fmt.Errorf("BACKUP_MODE_RULES: %w", err)
+	}
+	return &backupModes{config: config, patterns: patterns}, nil
+}
+
+func (m *backupModes) modeFor(repo model.Repo) string {
+	if pattern := matchingPattern(m.patterns, repo); pattern != "" {
+		for _, rule := range m.config.BackupModeRules {
+			if rule.Pattern == pattern {
+				return rule.Mode
+			}
+		}
+	}
+	if source, ok := sourceFor(m.config, repo); ok && source.Mode != "" {
+		return source.Mode
+	}
+	if m.config.BackupMode != "" {
+		return m.config.BackupMode
+	}
+	return model.BackupModeSnapshot
+}
+
+func includesSnapshot(mode string) bool {
+	return mode != model.BackupModeMirror
+}
+
+func includesMirror(mode string) bool {
+	return mode == model.BackupModeMirror || mode == model.BackupModeBoth
+}
+
+// backupArtifactsMatch reports whether _Repos holds exactly what the repo's mode stores, so an unchanged repo
+// whose mode was just switched is backed up again instead of skipped
+func backupArtifactsMatch(repoPath string, mode string) bool {
+	_, err := os.Stat("_Repos/" + helper.ArchiveFileName(repoPath))
+	if (err == nil) != includesSnapshot(mode) {
+		return false
+	}
+	_, err = os.Stat(path.Join("_Repos", helper.MirrorDirName(repoPath), mirrorManifestName))
+	return (err == nil) == includesMirror(mode)
+}
+
+// staleArtifacts lists what a previous run stored for the mode the repo no longer uses
+func staleArtifacts(repoPath string, mode string) []string {
+	var stale []string
+	if !includesSnapshot(mode) {
+		// The working copy of the tarball is already gone; git rm still has to drop it from the index
+		stale = append(stale, helper.ArchiveFileName(repoPath))
+	}
+	if !includesMirror(mode) {
+		if _, err := os.Stat("_Repos/" + helper.MirrorDirName(repoPath)); err == nil {
+			stale = append(stale, helper.MirrorDirName(repoPath))
+		}
+	}
+	return stale
+}
+
+// mirrorRepo stores every ref of the repo's cached bare mirror as verified git bundles in <owner>/<repo>.mirror. With a previous state it only bundles the objects added since the refs that were
+// last pushed, until the chain is long or old enough to start over with a full bundle. The returned state is
+// saved once the bundle has been pushed.
+func mirrorRepo(hr repoHashResult, mirrorDir string, previous *model.MirrorState, config *model.ConfigModel) (model.MirrorState, error) {
+	state := model.MirrorState{FullName: hr.FullName}
+
+	refs, head, err := helper.MirrorRefs(mirrorDir)
+	if err != nil {
+		return state, err
+	}
+	state.Refs = refs
+
+	localDir, err := filepath.Abs(path.Join("_Repos", helper.MirrorDirName(hr.RepoPath)))
+	if err != nil {
+		return state, err
+	}
+
+	var bundle *model.MirrorBundle
+	if basis := incrementalBasis(mirrorDir, localDir, previous, config); len(basis) > 0 {
+		state.Bundles = previous.Bundles
+		name := fmt.Sprintf("incremental-%03d.bundle", len(previous.Bundles))
+		bundle, err = writeBundle(mirrorDir, localDir, name, model.MirrorBundleIncremental, basis)
+	} else {
+		if err := os.RemoveAll(localDir); err != nil {
+			return state, err
+		}
+		bundle, err = writeBundle(mirrorDir, localDir, mirrorBundleName, model.MirrorBundleFull, nil)
+	}
+	if err != nil {
+		return state, err
+	}
+	// Refs that moved onto existing commits or were deleted add no objects; the manifest alone records them
+	if bundle != nil {
+		state.Bundles = append(state.Bundles, *bundle)
+	}
+
+	manifest := model.MirrorManifest{
+		FormatVersion: model.MirrorFormatVersion,
+		UpdatedAt:     time.Now().UTC(),
+		Head:          head,
+		Refs:          refs,
+		Bundles:       state.Bundles,
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return state, err
+	}
+	if err := os.WriteFile(filepath.Join(localDir, mirrorManifestName), append(data, '\n'), 0o644); err != nil {
+		return state, fmt.Errorf("failed to write %s: %w", mirrorManifestName, err)
+	}
+
+	return state, nil
+}
+
+// incrementalBasis returns the previously pushed ref targets still present in the mirror, or nil when
+// the next bundle has to be a full one: no chain yet, a chain at its length or age limit, or bundles missing
+// from _Repos
+func incrementalBasis(mirrorDir string, localDir string, previous *model.MirrorState, config *model.ConfigModel) []string {
+	if previous == nil || len(previous.Bundles) == 0 || previous.Bundles[0].Kind != model.MirrorBundleFull {
+		return nil
+	}
+	if len(previous.Bundles)-1 >= config.MirrorIncrementals {
+		return nil
+	}
+	if time.Since(previous.Bundles[0].CreatedAt) >= time.Duration(config.MirrorFullDays)*24*time.Hour {
+		return nil
+	}
+
+	// Leftovers of a run whose push failed are not part of the chain and are rebuilt
+	entries, err := os.ReadDir(localDir)
+	if err != nil {
+		return nil
+	}
+	chain := make(map[string]bool)
+	for _, bundle := range previous.Bundles {
+		files := bundle.Parts
+		if len(files) == 0 {
+			files = []string{bundle.Name}
+		}
+		for _, file := range files {
+			chain[file] = true
+			if _, err := os.Stat(filepath.Join(localDir, file)); err != nil {
+				return nil
+			}
+		}
+	}
+	for _, entry := range entries {
+		if !chain[entry.Name()] && entry.Name() != mirrorManifestName {
+			os.RemoveAll(filepath.Join(localDir, entry.Name()))
+		}
+	}
+
+	seen := make(map[string]bool, len(previous.Refs))
+	var hashes []string
+	for _, hash := range previous.Refs {
+		if !seen[hash] {
+			seen[hash] = true
+			hashes = append(hashes, hash)
+		}
+	}
+	sort.Strings(hashes)
+
+	// Force-pushed history may be gone from the mirror and cannot be excluded; the rest of the basis still applies
+	basis, err := helper.ExistingObjects(mirrorDir, hashes)
+	if err != nil {
+		return nil
+	}
+	return basis
+}
+
+// writeBundle creates and verifies one bundle in localDir and splits it when it is too large for a git blob.
+// It returns nil when there is nothing new to bundle.
+func writeBundle(mirrorDir string, localDir string, name string, kind string, exclude []string) (*model.MirrorBundle, error) {
+	if err := os.MkdirAll(localDir, 0o755); err != nil {
+		return nil, err
+	}
+
+	file := filepath.Join(localDir, name)
+	if err := helper.CreateBundle(mirrorDir, file, exclude); err != nil {
+		if errors.Is(err, helper.ErrEmptyBundle) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	bundle := &model.MirrorBundle{Name: name, Kind: kind, CreatedAt: time.Now().UTC()}
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+	bundle.SizeBytes = info.Size()
+	if bundle.SHA256, err = helper.FileSHA256(file); err != nil {
+		return nil, err
+	}
+
+	if info.Size() > maxGitHubBlobSize {
+		parts, err := helper.SplitFile(file, maxGitHubBlobSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, part := range parts {
+			bundle.Parts = append(bundle.Parts, filepath.Base(part))
+		}
+	}
+
+	return bundle, nil
+}
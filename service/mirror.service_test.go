@@ -0,0 +1,171 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MishraShardendu22/github-backup/model"
+	"github.com/MishraShardendu22/github-backup/service/helper"
+)
+
+// commitFile writes name in the work tree at dir and commits it, returning the new commit
+func commitFile(t *testing.T, dir string, name string) string {
+	t.Helper()
+
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(name+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, dir, "add", name)
+	runGit(t, dir, "-c", "user.name=Backup Test", "-c", "user.email=backup@example.com", "commit", "-q", "-m", "add "+name)
+	return runGit(t, dir, "rev-parse", "HEAD")
+}
+
+func TestIncrementalBasis(t *testing.T) {
+	repo := t.TempDir()
+	runGit(t, repo, "init", "-q")
+	first := commitFile(t, repo, "a.txt")
+	second := commitFile(t, repo, "b.txt")
+
+	config := &model.ConfigModel{MirrorIncrementals: 3, MirrorFullDays: 30}
+	chain := func(fullAge time.Duration, incrementals int) *model.MirrorState {
+		state := &model.MirrorState{
+			FullName: "acme/api",
+			Refs: map[string]string{
+				"HEAD":             second,
+				"refs/heads/main":  second,
+				"refs/tags/v1.0.0": first,
+				// A branch force-pushed away upstream: its commit is no longer in the mirror
+				"refs/heads/rewritten": "0123456789abcdef0123456789abcdef01234567",
+			},
+			Bundles: []model.MirrorBundle{{Name: mirrorBundleName, Kind: model.MirrorBundleFull, CreatedAt: time.Now().Add(-fullAge)}},
+		}
+		for i := 1; i <= incrementals; i++ {
+			state.Bundles = append(state.Bundles, model.MirrorBundle{
+				Name: fmt.Sprintf("incremental-%03d.bundle", i),
+				Kind: model.MirrorBundleIncremental,
+			})
+		}
+		return state
+	}
+
+	tests := []struct {
+		name     string
+		previous *model.MirrorState
+		missing  string
+		want     []string
+	}{
+		{name: "no previous state", previous: nil},
+		{name: "chain without a full bundle", previous: &model.MirrorState{Bundles: []model.MirrorBundle{{Name: "incremental-001.bundle", Kind: model.MirrorBundleIncremental}}}},
+		{name: "chain at its length limit", previous: chain(time.Hour, 3)},
+		{name: "full bundle too old", previous: chain(31*24*time.Hour, 1)},
+		{name: "bundle missing from _Repos", previous: chain(time.Hour, 2), missing: "incremental-001.bundle"},
+		{name: "incremental", previous: chain(time.Hour, 2), want: []string{first, second}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			localDir := t.TempDir()
+			files := []string{mirrorManifestName, "leftover.bundle"}
+			if tt.previous != nil {
+				for _, bundle := range tt.previous.Bundles {
+					if bundle.Name != tt.missing {
+						files = append(files, bundle.Name)
+					}
+				}
+			}
+			for _, file := range files {
+				if err := os.WriteFile(filepath.Join(localDir, file), nil, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			basis := incrementalBasis(repo, localDir, tt.previous, config)
+			sort.Strings(tt.want)
+			if strings.Join(basis, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("basis = %v, want %v", basis, tt.want)
+			}
+
+			if tt.want == nil {
+				return
+			}
+			if _, err := os.Stat(filepath.Join(localDir, "leftover.bundle")); err == nil {
+				t.Error("bundle outside the chain was kept")
+			}
+			for _, file := range files {
+				if _, err := os.Stat(filepath.Join(localDir, file)); err != nil && file != "leftover.bundle" {
+					t.Errorf("%s removed", file)
+				}
+			}
+		})
+	}
+}
+
+func TestMirrorBundleChain(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	upstream := filepath.Join(dir, "upstream")
+	runGit(t, dir, "init", "-q", upstream)
+	runGit(t, upstream, "checkout", "-q", "-b", "main")
+	first := commitFile(t, upstream, "a.txt")
+
+	config := &model.ConfigModel{MirrorCacheDir: "_MirrorCache", MirrorIncrementals: 5, MirrorFullDays: 30}
+	hr := repoHashResult{
+		Repo:     model.Repo{FullName: "acme/api", DefaultBranch: "main"},
+		FullName: "acme/api",
+		RepoPath: "acme/api",
+		URL:      upstream,
+	}
+
+	mirrorDir, err := syncMirrorCache(config, hr, nil)
+	if err != nil {
+		t.Fatalf("syncMirrorCache: %v", err)
+	}
+	if want, _ := filepath.Abs("_MirrorCache/acme/api.git"); mirrorDir != want {
+		t.Errorf("mirror dir = %s, want %s", mirrorDir, want)
+	}
+	full, err := mirrorRepo(hr, mirrorDir, nil, config)
+	if err != nil {
+		t.Fatalf("full mirror: %v", err)
+	}
+	if len(full.Bundles) != 1 || full.Bundles[0].Kind != model.MirrorBundleFull {
+		t.Fatalf("bundles after the first run = %+v", full.Bundles)
+	}
+
+	commitFile(t, upstream, "b.txt")
+	runGit(t, upstream, "tag", "v1.0.0")
+	runGit(t, upstream, "branch", "feature")
+
+	if _, err := syncMirrorCache(config, hr, nil); err != nil {
+		t.Fatalf("syncMirrorCache fetch: %v", err)
+	}
+	incremental, err := mirrorRepo(hr, mirrorDir, &full, config)
+	if err != nil {
+		t.Fatalf("incremental mirror: %v", err)
+	}
+	if len(incremental.Bundles) != 2 || incremental.Bundles[1].Kind != model.MirrorBundleIncremental ||
+		incremental.Bundles[1].Name != "incremental-001.bundle" {
+		t.Fatalf("bundles after the second run = %+v", incremental.Bundles)
+	}
+	header, err := os.ReadFile(filepath.Join("_Repos", helper.MirrorDirName(hr.RepoPath), "incremental-001.bundle"))
+	if err != nil || !strings.Contains(string(header), "\n-"+first) {
+		t.Errorf("incremental bundle does not build on the first commit: %v", err)
+	}
+
+	// Fetching the chain in order into an empty repository restores every ref
+	restored := filepath.Join(dir, "restored.git")
+	runGit(t, dir, "init", "-q", "--bare", restored)
+	for _, bundle := range incremental.Bundles {
+		runGit(t, restored, "fetch", "-q", filepath.Join(dir, "_Repos", helper.MirrorDirName(hr.RepoPath), bundle.Name), "refs/*:refs/*")
+	}
+	want := runGit(t, upstream, "for-each-ref", "--format=%(refname) %(objectname)")
+	if got := runGit(t, restored, "for-each-ref", "--format=%(refname) %(objectname)"); got != want {
+		t.Errorf("restored refs:\n%s\nwant:\n%s", got, want)
+	}
+	if !backupArtifactsMatch(hr.RepoPath, model.BackupModeMirror) {
+		t.Error("mirror manifest missing from _Repos")
+	}
+}
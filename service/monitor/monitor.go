@@ -15,9 +15,10 @@ import (
 var migrationSQL string
 
 type Monitor struct {
-	pool    *pgxpool.Pool
-	runID   int
-	enabled bool
+	pool          *pgxpool.Pool
+	runID         int
+	enabled       bool
+	partialReason string
 }
 
 var instance *Monitor
@@ -83,12 +84,24 @@ func (m *Monitor) StartRun(totalRepos int) {
 	}
 }
 
+// MarkPartial flags the current run as having worked from an incomplete repository list
+func (m *Monitor) MarkPartial(reason string) {
+	m.partialReason = reason
+}
+
 func (m *Monitor) CompleteRun(successful, failed, skipped int, durationMs int64, errMsg string) {
 	if !m.enabled || m.runID == 0 {
 		return
 	}
 	status := "completed"
-	if errMsg != "" {
+	if m.partialReason != "" {
+		status = "partial"
+		if errMsg == "" {
+			errMsg = m.partialReason
+		} else {
+			errMsg = m.partialReason + "; " + errMsg
+		}
+	} else if errMsg != "" {
 		status = "failed"
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -101,16 +114,21 @@ func (m *Monitor) CompleteRun(successful, failed, skipped int, durationMs int64,
 	}
 }
 
-func (m *Monitor) LogRepoResult(repoFullName, status, commitHash string, archiveSize, durationMs int64, errMsg string) {
+// LogRepoResult records one repo's outcome; changedRefs lists the refs that triggered the backup as
+// +ref (created), ~ref (moved) or -ref (deleted)
+func (m *Monitor) LogRepoResult(repoFullName, status, commitHash string, changedRefs []string, archiveSize, durationMs int64, errMsg string) {
 	if !m.enabled || m.runID == 0 {
 		return
 	}
+	if changedRefs == nil {
+		changedRefs = []string{}
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	_, err := m.pool.Exec(ctx,
-		`INSERT INTO backup_results (run_id, repo_full_name, status, commit_hash, archive_size_bytes, duration_ms, error_message)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
-		m.runID, repoFullName, status, commitHash, archiveSize, durationMs, errMsg)
+		`INSERT INTO backup_results (run_id, repo_full_name, status, commit_hash, changed_refs, archive_size_bytes, duration_ms, error_message)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		m.runID, repoFullName, status, commitHash, changedRefs, archiveSize, durationMs, errMsg)
 	if err != nil {
 		util.Logger().Error("Monitor: failed to log repo result", zap.String("repo", repoFullName), zap.Error(err))
 	}
@@ -0,0 +1,266 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/MishraShardendu22/github-backup/controller"
+	"github.com/MishraShardendu22/github-backup/model"
+	"github.com/MishraShardendu22/github-backup/service/helper"
+)
+
+const (
+	checkPass = "PASS"
+	checkWarn = "WARN"
+	checkFail = "FAIL"
+
+	// tokenExpiryWarning flags tokens that will expire before the next few nightly runs
+	tokenExpiryWarning = 7 * 24 * time.Hour
+	lowRateLimitRatio  = 0.1
+)
+
+type preflightCheck struct {
+	Name   string
+	Target string
+	Status string
+	Detail string
+}
+
+// RunPreflight checks everything a backup run depends on without changing anything: tokens, SSH clone
+// hosts, push access to the backup remote, disk space and required binaries. It prints a table and returns
+// an error when any check failed, so a scheduler can alert before the nightly run.
+func RunPreflight(cfg *model.ConfigModel) error {
+	var checks []preflightCheck
+	checks = append(checks, checkBinaries()...)
+	checks = append(checks, checkSourceTokens(cfg.Sources)...)
+	checks = append(checks, checkSSHHosts(cfg)...)
+	checks = append(checks, checkPushAccess(cfg))
+	checks = append(checks, checkDiskSpace(cfg))
+
+	printPreflightTable(checks)
+
+	failed := 0
+	for _, check := range checks {
+		if check.Status == checkFail {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("preflight failed: %d of %d checks failed", failed, len(checks))
+	}
+
+	return nil
+}
+
+func checkBinaries() []preflightCheck {
+	var checks []preflightCheck
+	for _, binary := range []string{"git", "tar"} {
+		check := preflightCheck{Name: "binary", Target: binary}
+		if path, err := exec.LookPath(binary); err != nil {
+			check.Status, check.Detail = checkFail, "not found in PATH"
+		} else {
+			check.Status, check.Detail = checkPass, path
+		}
+		checks = append(checks, check)
+	}
+
+	return checks
+}
+
+func checkSourceTokens(sources []model.Source) []preflightCheck {
+	if len(sources) == 0 {
+		return []preflightCheck{{Name: "sources", Status: checkFail, Detail: "no discovery sources configured"}}
+	}
+
+	var checks []preflightCheck
+	for _, source := range sources {
+		if source.Provider == model.ProviderGit {
+			checks = append(checks, checkRemotes(source)...)
+			continue
+		}
+
+		check := preflightCheck{Name: "token", Target: source.Name}
+		switch {
+		case source.Provider != model.ProviderGitHub:
+			check.Status, check.Detail = checkForgeToken(source)
+		case source.App != nil:
+			check.Status, check.Detail = checkAppToken(source)
+		default:
+			check.Status, check.Detail = checkGitHubToken(source)
+		}
+		checks = append(checks, check)
+	}
+
+	return checks
+}
+
+// checkRemotes ls-remotes every configured remote, since plain git sources have no token to validate
+func checkRemotes(source model.Source) []preflightCheck {
+	var checks []preflightCheck
+	for _, remote := range source.Remotes {
+		check := preflightCheck{Name: "remote", Target: remote.Name}
+		if hash, err := helper.GetRemoteHeadHash(remote.URL, nil); err != nil {
+			check.Status, check.Detail = checkFail, err.Error()
+		} else {
+			check.Status, check.Detail = checkPass, "HEAD "+hash
+		}
+		checks = append(checks, check)
+	}
+
+	return checks
+}
+
+func checkForgeToken(source model.Source) (string, string) {
+	if source.Token == "" {
+		return checkWarn, "no token; only public repositories are visible"
+	}
+
+	login, err := controller.CheckForgeToken(source.APIURL, source.Token)
+	if err != nil {
+		return checkFail, err.Error()
+	}
+	return checkPass, "authenticated as " + login
+}
+
+func checkAppToken(source model.Source) (string, string) {
+	token, err := controller.SourceToken(source)
+	if err != nil {
+		return checkFail, err.Error()
+	}
+
+	info, err := controller.InspectGitHubToken(source.APIURL, token, true)
+	if err != nil {
+		return checkFail, err.Error()
+	}
+
+	status, rate := rateLimitStatus(info)
+	return status, fmt.Sprintf("app %d installation %d; %s", source.App.AppID, source.App.InstallationID, rate)
+}
+
+func checkGitHubToken(source model.Source) (string, string) {
+	if source.Token == "" {
+		if source.Kind == model.SourceKindAuthenticatedUser {
+			return checkFail, "no token configured"
+		}
+		return checkWarn, "no token; unauthenticated requests are limited to 60 per hour"
+	}
+
+	info, err := controller.InspectGitHubToken(source.APIURL, source.Token, false)
+	if err != nil {
+		return checkFail, err.Error()
+	}
+
+	status, rate := rateLimitStatus(info)
+	details := []string{"user " + info.Login}
+
+	if info.ScopesKnown {
+		details = append(details, "scopes "+strings.Join(info.Scopes, " "))
+		if source.Kind == model.SourceKindAuthenticatedUser && !containsFold(info.Scopes, "repo") {
+			status = checkFail
+			details = append(details, "missing repo scope for private repositories")
+		}
+	} else {
+		details = append(details, "fine-grained token")
+	}
+
+	if !info.ExpiresAt.IsZero() {
+		remaining := time.Until(info.ExpiresAt)
+		details = append(details, "expires "+info.ExpiresAt.Format("2006-01-02"))
+		if remaining <= 0 {
+			status = checkFail
+		} else if remaining < tokenExpiryWarning && status == checkPass {
+			status = checkWarn
+		}
+	}
+
+	return status, strings.Join(append(details, rate), "; ")
+}
+
+// rateLimitStatus fails when the core limit is spent and warns when it is nearly spent
+func rateLimitStatus(info controller.TokenInfo) (string, string) {
+	rate := fmt.Sprintf("rate limit %d/%d", info.RateRemaining, info.RateLimit)
+	if info.RateLimit == 0 {
+		return checkPass, "rate limit unknown"
+	}
+	if info.RateRemaining == 0 {
+		return checkFail, rate + " until " + info.RateReset.Format(time.Kitchen)
+	}
+	if float64(info.RateRemaining) < float64(info.RateLimit)*lowRateLimitRatio {
+		return checkWarn, rate
+	}
+	return checkPass, rate
+}
+
+func checkSSHHosts(cfg *model.ConfigModel) []preflightCheck {
+	hosts := []string{cfg.GitCloneHost}
+	for _, source := range cfg.Sources {
+		hosts = append(hosts, source.CloneHost)
+	}
+
+	var checks []preflightCheck
+	seen := make(map[string]bool)
+	for _, host := range hosts {
+		target, ok := helper.SSHTarget(host)
+		if !ok || seen[host] {
+			continue
+		}
+		seen[host] = true
+
+		check := preflightCheck{Name: "ssh", Target: host}
+		if greeting, err := helper.CheckSSHHost(target); err != nil {
+			check.Status, check.Detail = checkFail, err.Error()
+		} else {
+			check.Status, check.Detail = checkPass, greeting
+		}
+		checks = append(checks, check)
+	}
+
+	return checks
+}
+
+func checkPushAccess(cfg *model.ConfigModel) preflightCheck {
+	check := preflightCheck{Name: "push", Target: cfg.BackupRepoPath}
+	if cfg.BackupRepoPath == "" {
+		check.Status, check.Detail = checkFail, "BACKUP_REPO_PATH is not set"
+		return check
+	}
+
+	if err := helper.DryRunPush(cfg.BackupRepoPath); err != nil {
+		check.Status, check.Detail = checkFail, err.Error()
+		return check
+	}
+
+	check.Status, check.Detail = checkPass, "dry-run push accepted"
+	return check
+}
+
+func checkDiskSpace(cfg *model.ConfigModel) preflightCheck {
+	check := preflightCheck{Name: "disk", Target: "."}
+	free, err := helper.FreeDiskBytes(".")
+	if err != nil {
+		check.Status, check.Detail = checkFail, err.Error()
+		return check
+	}
+
+	freeGB := float64(free) / (1 << 30)
+	check.Detail = fmt.Sprintf("%.1f GB free (minimum %d GB)", freeGB, cfg.PreflightMinFreeGB)
+	check.Status = checkPass
+	if freeGB < float64(cfg.PreflightMinFreeGB) {
+		check.Status = checkFail
+	}
+
+	return check
+}
+
+func printPreflightTable(checks []preflightCheck) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHECK\tTARGET\tSTATUS\tDETAIL")
+	for _, check := range checks {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", check.Name, check.Target, check.Status, check.Detail)
+	}
+	w.Flush()
+}
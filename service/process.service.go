@@ -2,9 +2,10 @@ package service
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -24,23 +25,48 @@ const (
 )
 
 type repoResult struct {
+	Repo        model.Repo
 	FullName    string
 	RepoName    string
+	RepoPath    string
 	URL         string
 	CurrentHash string
-	Err         error
+	Mode        string
+	// Refs is the remote ref set to record once the push succeeded, ChangedRefs its difference to the last backup
+	Refs        model.RepoRefs
+	ChangedRefs []string
+	// Mirror is the bundle chain to record once the push succeeded
+	Mirror *model.MirrorState
+	Err    error
 }
 
 type repoHashResult struct {
+	Repo        model.Repo
 	FullName    string
 	RepoName    string
+	RepoPath    string
 	URL         string
 	CurrentHash string
+	Mode        string
+	Refs        model.RepoRefs
+	ChangedRefs []string
 	HashErr     error
 	Skipped     bool
+	// RecordRefs marks an unchanged repo whose refs or push time were not recorded yet; they are saved without a backup
+	RecordRefs bool
+	// NoWiki marks a wiki entry whose wiki repo was never created; it is dropped without a result
+	NoWiki bool
 }
 
-func ProcessRepos(repoNames []string, config *model.ConfigModel, db *sql.DB) {
+func ProcessRepos(discovery DiscoveryResult, config *model.ConfigModel, db *sql.DB) {
+	repos := discovery.Repos
+
+	modes, err := newBackupModes(config)
+	if err != nil {
+		util.ErrorHandler(err)
+		return
+	}
+
 	if err := helper.EnsureReposDirExists(); err != nil {
 		util.ErrorHandler(err)
 		return
@@ -51,28 +77,58 @@ func ProcessRepos(repoNames []string, config *model.ConfigModel, db *sql.DB) {
 		return
 	}
 
-	processDeletedRepos(repoNames, db)
+	migrateFlatArchives(db)
+	detectRenamedRepos(discovery.Present(), config, db)
+	restoreTombstonedRepos(discovery.Present(), db)
+	if discovery.Complete() {
+		processDeletedRepos(discovery.Present(), config, db)
+	} else {
+		util.Logger().Warn("Skipping deleted-repo cleanup; discovery was incomplete",
+			zap.Strings("failed_sources", discovery.FailedSources),
+		)
+	}
+	purgeExpiredTombstones(config, db)
+	recordRepoMetadata(repos, db)
+	if config.ExportIssues {
+		exportIssueTrackers(repos, config, db)
+	}
+	if config.BackupReleases {
+		backupReleases(repos, config, db)
+	}
+	if config.SnapshotGovernance {
+		snapshotGovernance(discovery, config)
+	}
 
 	util.Logger().Info("Starting repository backup")
 
 	mon := monitor.Get()
 	start := time.Now()
 	if mon != nil {
-		mon.StartRun(len(repoNames))
-		mon.Log("info", fmt.Sprintf("Starting backup of %d repositories", len(repoNames)), "")
+		mon.Log("info", fmt.Sprintf("Starting backup of %d repositories", len(repos)), "")
 	}
 
 	util.Logger().Info("Phase 1: Checking repository hashes",
-		zap.Int("total", len(repoNames)),
+		zap.Int("total", len(repos)),
 		zap.Int("workers", hashCheckWorkers),
 	)
-	hashResults := parallelHashCheck(repoNames, db)
+	hashResults := parallelHashCheck(repos, modes, config, db)
 
 	var toClone []repoHashResult
 	skippedCount := 0
 	for _, hr := range hashResults {
+		if hr.NoWiki {
+			continue
+		}
 		if hr.Skipped {
 			skippedCount++
+			if hr.RecordRefs && db != nil {
+				if err := database.SaveRepoRefs(db, hr.Refs); err != nil {
+					util.Logger().Warn("Failed to store repository refs",
+						zap.String("repository", hr.FullName),
+						zap.Error(err),
+					)
+				}
+			}
 			continue
 		}
 		toClone = append(toClone, hr)
@@ -89,7 +145,7 @@ func ProcessRepos(repoNames []string, config *model.ConfigModel, db *sql.DB) {
 			mon.CompleteRun(0, 0, skippedCount, durationMs, "")
 			mon.Log("info", fmt.Sprintf("All %d repos up to date, nothing to clone", skippedCount), "")
 		}
-		printBackupSummary(repoNames, 0, skippedCount, nil)
+		printBackupSummary(repos, discovery.Sources, 0, skippedCount, nil)
 		return
 	}
 
@@ -116,7 +172,7 @@ func ProcessRepos(repoNames []string, config *model.ConfigModel, db *sql.DB) {
 		)
 
 		// Clone + archive in parallel (5 at a time)
-		cloneResults := parallelCloneAndArchive(batch)
+		cloneResults := parallelCloneAndArchive(batch, config, db)
 
 		// Commit + push EACH repo individually (serial, one by one)
 		for _, res := range cloneResults {
@@ -124,58 +180,95 @@ func ProcessRepos(repoNames []string, config *model.ConfigModel, db *sql.DB) {
 				recordFailure(db, res.FullName, res.Err)
 				failedRepos = append(failedRepos, res.FullName)
 				if mon != nil {
-					mon.LogRepoResult(res.FullName, "failed", res.CurrentHash, 0, 0, res.Err.Error())
+					mon.LogRepoResult(res.FullName, "failed", res.CurrentHash, res.ChangedRefs, 0, 0, res.Err.Error())
 					mon.Log("error", "Backup failed: "+res.Err.Error(), res.FullName)
 					mon.UpdateProgress(successCount, len(failedRepos), skippedCount)
 				}
 				continue
 			}
 
-			// Stage the tarball
-			tarball := fmt.Sprintf("%s.tar.gz", res.RepoName)
-			archivePath := fmt.Sprintf("_Repos/%s", tarball)
-			info, err := os.Stat(archivePath)
-			if err != nil {
-				util.Logger().Warn("Failed to inspect archive size; skipping repository",
-					zap.String("repository", res.FullName),
-					zap.Error(err),
-				)
-				failedRepos = append(failedRepos, res.FullName)
-				if mon != nil {
-					mon.LogRepoResult(res.FullName, "failed", res.CurrentHash, 0, 0, err.Error())
-					mon.Log("error", "Archive inspection failed: "+err.Error(), res.FullName)
-					mon.UpdateProgress(successCount, len(failedRepos), skippedCount)
+			var stagePaths []string
+			if includesSnapshot(res.Mode) {
+				// Stage the tarball
+				tarball := helper.ArchiveFileName(res.RepoPath)
+				archivePath := fmt.Sprintf("_Repos/%s", tarball)
+				info, err := os.Stat(archivePath)
+				if err != nil {
+					util.Logger().Warn("Failed to inspect archive size; skipping repository",
+						zap.String("repository", res.FullName),
+						zap.Error(err),
+					)
+					failedRepos = append(failedRepos, res.FullName)
+					if mon != nil {
+						mon.LogRepoResult(res.FullName, "failed", res.CurrentHash, res.ChangedRefs, 0, 0, err.Error())
+						mon.Log("error", "Archive inspection failed: "+err.Error(), res.FullName)
+						mon.UpdateProgress(successCount, len(failedRepos), skippedCount)
+					}
+					continue
 				}
-				continue
+
+				if info.Size() > maxGitHubBlobSize && res.Mode == model.BackupModeSnapshot {
+					util.Logger().Warn(
+						fmt.Sprintf("Skipping repo %s: archive exceeds GitHub blob limit (%d MB)",
+							res.FullName,
+							info.Size()/(1024*1024),
+						),
+					)
+					skippedCount++
+					if mon != nil {
+						mon.Log("warn", fmt.Sprintf("Skipping oversized archive for %s", res.FullName), res.FullName)
+						mon.UpdateProgress(successCount, len(failedRepos), skippedCount)
+					}
+					continue
+				}
+
+				if info.Size() > maxGitHubBlobSize {
+					// The bundle already carries the full history, so only the snapshot is dropped
+					util.Logger().Warn("Snapshot archive exceeds GitHub blob limit; storing the bundle only",
+						zap.String("repository", res.FullName),
+						zap.Int64("size_mb", info.Size()/(1024*1024)),
+					)
+					if mon != nil {
+						mon.Log("warn", fmt.Sprintf("Snapshot archive for %s is oversized; bundle stored only", res.FullName), res.FullName)
+					}
+				} else {
+					stagePaths = append(stagePaths, tarball)
+				}
+			}
+			if includesMirror(res.Mode) {
+				stagePaths = append(stagePaths, helper.MirrorDirName(res.RepoPath))
 			}
 
-			if info.Size() > maxGitHubBlobSize {
-				util.Logger().Warn(
-					fmt.Sprintf("Skipping repo %s: archive exceeds GitHub blob limit (%d MB)",
-						res.FullName,
-						info.Size()/(1024*1024),
-					),
-				)
-				skippedCount++
-				if mon != nil {
-					mon.Log("warn", fmt.Sprintf("Skipping oversized archive for %s", res.FullName), res.FullName)
-					mon.UpdateProgress(successCount, len(failedRepos), skippedCount)
+			if stale := staleArtifacts(res.RepoPath, res.Mode); len(stale) > 0 {
+				if err := helper.RemoveTrackedFiles(stale...); err != nil {
+					util.Logger().Warn("Failed to remove artifacts of the previous backup mode",
+						zap.String("repository", res.FullName),
+						zap.Error(err),
+					)
 				}
-				continue
 			}
 
-			commitMsg := helper.BuildCommitMessage(res.RepoName)
-			helper.StageAndCommitRepo(tarball, commitMsg)
+			if err := helper.WriteRepoMetadata(res.Repo, res.RepoPath, res.CurrentHash); err != nil {
+				util.Logger().Warn("Failed to write repository metadata file",
+					zap.String("repository", res.FullName),
+					zap.Error(err),
+				)
+			} else {
+				stagePaths = append(stagePaths, helper.MetadataFileName(res.RepoPath))
+			}
+
+			commitMsg := helper.BuildCommitMessage(res.FullName)
+			helper.StageAndCommitRepo(stagePaths, commitMsg)
 
 			// Push THIS repo immediately
-			if err := helper.PushBackupRepo(res.RepoName); err != nil {
+			if err := helper.PushBackupRepo(res.FullName); err != nil {
 				util.Logger().Error("Failed to push repo",
 					zap.String("repository", res.FullName),
 					zap.Error(err),
 				)
 				failedRepos = append(failedRepos, res.FullName)
 				if mon != nil {
-					mon.LogRepoResult(res.FullName, "failed", res.CurrentHash, 0, 0, "push failed: "+err.Error())
+					mon.LogRepoResult(res.FullName, "failed", res.CurrentHash, res.ChangedRefs, 0, 0, "push failed: "+err.Error())
 					mon.Log("error", "Push failed: "+err.Error(), res.FullName)
 					mon.UpdateProgress(successCount, len(failedRepos), skippedCount)
 				}
@@ -184,24 +277,43 @@ func ProcessRepos(repoNames []string, config *model.ConfigModel, db *sql.DB) {
 
 			// Update DB with new hash
 			if db != nil && res.CurrentHash != "" {
-				if err := database.UpsertRepo(db, res.RepoName, res.FullName, res.URL, res.CurrentHash); err != nil {
+				if err := database.UpsertRepo(db, res.Repo, res.RepoName, res.URL, res.CurrentHash); err != nil {
 					util.Logger().Warn("Failed to store repository hash",
 						zap.String("repository", res.FullName),
 						zap.Error(err),
 					)
 				}
 			}
+			if db != nil {
+				if err := database.SaveRepoRefs(db, res.Refs); err != nil {
+					util.Logger().Warn("Failed to store repository refs",
+						zap.String("repository", res.FullName),
+						zap.Error(err),
+					)
+				}
+			}
+			if db != nil && res.Mirror != nil {
+				if err := database.SaveMirrorState(db, *res.Mirror); err != nil {
+					util.Logger().Warn("Failed to store mirror state",
+						zap.String("repository", res.FullName),
+						zap.Error(err),
+					)
+				}
+			}
 
 			successCount++
 			util.Logger().Info("✓ Backed up and pushed",
 				zap.String("repository", res.FullName),
 			)
 			if mon != nil {
-				mon.LogRepoResult(res.FullName, "completed", res.CurrentHash, 0, 0, "")
+				mon.LogRepoResult(res.FullName, "completed", res.CurrentHash, res.ChangedRefs, 0, 0, "")
 				mon.Log("info", "Backup completed and pushed", res.FullName)
 				mon.UpdateProgress(successCount, len(failedRepos), skippedCount)
 			}
 		}
+
+		// Every clone of the batch went through the cache, so it is trimmed before the next batch grows it further
+		evictMirrorCache(config)
 	}
 
 	if mon != nil {
@@ -215,33 +327,63 @@ func ProcessRepos(repoNames []string, config *model.ConfigModel, db *sql.DB) {
 			successCount, len(failedRepos), skippedCount, durationMs), "")
 	}
 
-	printBackupSummary(repoNames, successCount, skippedCount, failedRepos)
+	printBackupSummary(repos, discovery.Sources, successCount, skippedCount, failedRepos)
 }
 
-func parallelHashCheck(repoNames []string, db *sql.DB) []repoHashResult {
-	results := make([]repoHashResult, len(repoNames))
+func parallelHashCheck(repos []model.Repo, modes *backupModes, config *model.ConfigModel, db *sql.DB) []repoHashResult {
+	results := make([]repoHashResult, len(repos))
 	var wg sync.WaitGroup
 	sem := make(chan struct{}, hashCheckWorkers)
 
-	for i, fullName := range repoNames {
+	var pushStates map[string]model.RepoPushState
+	if config.APIHashCheck && db != nil {
+		pushStates = apiPushStates(repos, config)
+	}
+	var apiSkipped int64
+
+	for i, repo := range repos {
 		wg.Add(1)
-		go func(idx int, fullName string) {
+		go func(idx int, repo model.Repo) {
 			defer wg.Done()
 			sem <- struct{}{}        // acquire
 			defer func() { <-sem }() // release
 
+			fullName := repo.FullName
 			repoName := helper.ExtractRepoName(fullName)
-			url := helper.BuildCloneURL(fullName)
+			url := cloneURLFor(config, repo)
 
 			hr := repoHashResult{
+				Repo:     repo,
 				FullName: fullName,
 				RepoName: repoName,
+				RepoPath: helper.RepoPath(fullName),
 				URL:      url,
+				Mode:     modes.modeFor(repo),
+			}
+
+			state, hasState := pushStates[fullName]
+			if hasState {
+				if head, unchanged := unchangedSincePush(db, hr, state); unchanged {
+					util.Logger().Info("Repository not pushed since last backup; skipping",
+						zap.String("repository", fullName),
+						zap.String("pushed_at", state.PushedAt),
+					)
+					hr.CurrentHash = head
+					hr.Skipped = true
+					atomic.AddInt64(&apiSkipped, 1)
+					results[idx] = hr
+					return
+				}
 			}
 
-			hash, err := helper.GetRemoteHeadHash(url)
+			refs, err := remoteRefs(config, repo, url)
+			if err != nil && repo.WikiOf != "" && errors.Is(err, helper.ErrRemoteMissing) {
+				hr.NoWiki = true
+				results[idx] = hr
+				return
+			}
 			if err != nil {
-				util.Logger().Warn("Failed to fetch remote hash; will clone anyway",
+				util.Logger().Warn("Failed to fetch remote refs; will clone anyway",
 					zap.String("repository", fullName),
 					zap.Error(err),
 				)
@@ -250,16 +392,33 @@ func parallelHashCheck(repoNames []string, db *sql.DB) []repoHashResult {
 				return
 			}
 
-			hr.CurrentHash = hash
+			hr.CurrentHash = refs["HEAD"]
+			hr.Refs = model.RepoRefs{FullName: fullName, Fingerprint: helper.RefsFingerprint(refs), PushedAt: state.PushedAt, Refs: refs}
 
 			if db != nil {
 				dbRepo, found, dbErr := database.GetRepo(db, fullName)
+				var stored model.RepoRefs
+				var tracked bool
+				if dbErr == nil {
+					stored, tracked, dbErr = database.GetRepoRefs(db, fullName)
+				}
 				if dbErr != nil {
 					util.Logger().Warn("Failed to read repo from DB; will clone anyway",
 						zap.String("repository", fullName),
 						zap.Error(dbErr),
 					)
-				} else if found && dbRepo.LatestCommitHash == hash {
+					results[idx] = hr
+					return
+				}
+
+				unchanged := tracked && stored.Fingerprint == hr.Refs.Fingerprint
+				// A new push time with the same refs is recorded so the next run can skip ls-remote
+				hr.RecordRefs = unchanged && stored.PushedAt != hr.Refs.PushedAt
+				// Repos backed up before refs were tracked fall back to their HEAD once, and record a baseline
+				if found && !tracked && dbRepo.LatestCommitHash == hr.CurrentHash {
+					unchanged, hr.RecordRefs = true, true
+				}
+				if found && unchanged && backupArtifactsMatch(hr.RepoPath, hr.Mode) {
 					util.Logger().Info("Repository unchanged; skipping",
 						zap.String("repository", fullName),
 					)
@@ -267,18 +426,65 @@ func parallelHashCheck(repoNames []string, db *sql.DB) []repoHashResult {
 					results[idx] = hr
 					return
 				}
+				hr.RecordRefs = false
+				if tracked {
+					hr.ChangedRefs = changedRefs(stored.Refs, refs)
+				}
+				if len(hr.ChangedRefs) > 0 {
+					util.Logger().Info("Repository refs changed",
+						zap.String("repository", fullName),
+						zap.Strings("refs", hr.ChangedRefs),
+					)
+				}
 			}
 
 			results[idx] = hr
-		}(i, fullName)
+		}(i, repo)
 	}
 
 	wg.Wait()
+	if pushStates != nil {
+		util.Logger().Info("API push state check complete",
+			zap.Int("push_states", len(pushStates)),
+			zap.Int64("skipped_without_ls_remote", apiSkipped),
+		)
+	}
 	return results
 }
 
+func remoteRefs(config *model.ConfigModel, repo model.Repo, url string) (map[string]string, error) {
+	gitEnv, err := gitEnvFor(config, repo)
+	if err != nil {
+		return nil, err
+	}
+	return helper.GetRemoteRefs(url, gitEnv)
+}
+
+// changedRefs lists the branches and tags that differ between two ref sets as +ref (created), ~ref (moved)
+// or -ref (deleted). HEAD is left out; a moved HEAD always shows as its branch.
+func changedRefs(previous, current map[string]string) []string {
+	var changed []string
+	for ref, hash := range current {
+		if ref == "HEAD" {
+			continue
+		}
+		if old, ok := previous[ref]; !ok {
+			changed = append(changed, "+"+ref)
+		} else if old != hash {
+			changed = append(changed, "~"+ref)
+		}
+	}
+	for ref := range previous {
+		if _, ok := current[ref]; !ok && ref != "HEAD" {
+			changed = append(changed, "-"+ref)
+		}
+	}
+	sort.Slice(changed, func(i, j int) bool { return changed[i][1:] < changed[j][1:] })
+	return changed
+}
+
 // parallelCloneAndArchive runs clone + archive with a worker pool
-func parallelCloneAndArchive(repos []repoHashResult) []repoResult {
+func parallelCloneAndArchive(repos []repoHashResult, config *model.ConfigModel, db *sql.DB) []repoResult {
 	results := make([]repoResult, len(repos))
 	var wg sync.WaitGroup
 	sem := make(chan struct{}, cloneWorkers)
@@ -301,17 +507,37 @@ func parallelCloneAndArchive(repos []repoHashResult) []repoResult {
 			)
 
 			res := repoResult{
+				Repo:        hr.Repo,
 				FullName:    hr.FullName,
 				RepoName:    hr.RepoName,
+				RepoPath:    hr.RepoPath,
 				URL:         hr.URL,
 				CurrentHash: hr.CurrentHash,
+				Mode:        hr.Mode,
+				Refs:        hr.Refs,
+				ChangedRefs: hr.ChangedRefs,
 			}
 
 			// Clean up any existing clone/archive
-			helper.CleanupExistingRepo(hr.RepoName)
-
-			// Clone with --bare --depth=1
-			if err := helper.CloneRepo(hr.URL, hr.RepoName); err != nil {
+			helper.CleanupExistingRepo(hr.RepoPath)
+
+			// Fetched per clone so installation tokens are refreshed during long runs
+			gitEnv, err := gitEnvFor(config, hr.Repo)
+			var mirrorDir string
+			if err == nil && includesMirror(hr.Mode) {
+				// Only objects new since the last run are downloaded into the cached mirror
+				mirrorDir, err = syncMirrorCache(config, hr, gitEnv)
+			}
+			if err == nil && includesSnapshot(hr.Mode) {
+				if mirrorDir != "" {
+					// Clone with --depth=1 from the local mirror
+					err = helper.CloneRepo("file://"+mirrorDir, hr.RepoPath, nil)
+				} else {
+					// Snapshot-only repos keep no cached mirror; a shallow clone is all they need
+					err = helper.CloneRepo(hr.URL, hr.RepoPath, gitEnv)
+				}
+			}
+			if err != nil {
 				util.Logger().Error("Failed to clone repository",
 					zap.String("repository", hr.FullName),
 					zap.Error(err),
@@ -321,19 +547,53 @@ func parallelCloneAndArchive(repos []repoHashResult) []repoResult {
 				return
 			}
 
-			// Archive: tar.gz the bare clone, then remove the .git dir
-			if err := helper.ArchiveRepo(hr.RepoName); err != nil {
-				util.Logger().Error("Failed to archive repository",
+			if includesSnapshot(hr.Mode) {
+				// Archive: tar.gz the working tree, then remove it
+				if err := helper.ArchiveRepo(hr.RepoPath); err != nil {
+					util.Logger().Error("Failed to archive repository",
+						zap.String("repository", hr.FullName),
+						zap.Error(err),
+					)
+					res.Err = err
+					results[idx] = res
+					return
+				}
+			}
+
+			if includesMirror(hr.Mode) {
+				var previous *model.MirrorState
+				if db != nil {
+					if state, found, err := database.GetMirrorState(db, hr.FullName); err != nil {
+						util.Logger().Warn("Failed to read mirror state; writing a full bundle",
+							zap.String("repository", hr.FullName),
+							zap.Error(err),
+						)
+					} else if found {
+						previous = &state
+					}
+				}
+
+				state, err := mirrorRepo(hr, mirrorDir, previous, config)
+				if err != nil {
+					util.Logger().Error("Failed to mirror repository",
+						zap.String("repository", hr.FullName),
+						zap.Error(err),
+					)
+					res.Err = err
+					results[idx] = res
+					return
+				}
+				res.Mirror = &state
+				util.Logger().Info("Mirror bundles verified",
 					zap.String("repository", hr.FullName),
-					zap.Error(err),
+					zap.Int("refs", len(state.Refs)),
+					zap.Int("chain_length", len(state.Bundles)),
 				)
-				res.Err = err
-				results[idx] = res
-				return
 			}
 
 			util.Logger().Info("Clone + archive complete",
 				zap.String("repository", hr.FullName),
+				zap.String("mode", hr.Mode),
 			)
 
 			results[idx] = res
@@ -344,105 +604,32 @@ func parallelCloneAndArchive(repos []repoHashResult) []repoResult {
 	return results
 }
 
-// processDeletedRepos cleans up repos that exist in DB but are no longer on GitHub — fully parallel
-func processDeletedRepos(currentRepoNames []string, db *sql.DB) {
+// recordRepoMetadata keeps the SQLite metadata columns and history current for every discovered repo,
+// including the ones whose code is unchanged and will be skipped
+func recordRepoMetadata(repos []model.Repo, db *sql.DB) {
 	if db == nil {
 		return
 	}
 
-	dbRepos, err := database.GetAllReposFromDB(db)
-	if err != nil {
-		util.Logger().Warn("Failed to fetch repos from DB for cleanup", zap.Error(err))
-		return
-	}
-
-	if len(dbRepos) == 0 {
-		return
-	}
-
-	// Build set of current repo names for O(1) lookup
-	currentSet := make(map[string]bool, len(currentRepoNames))
-	for _, name := range currentRepoNames {
-		currentSet[name] = true
-	}
-
-	// Find repos to delete
-	var toDelete []model.RepoRecord
-	for _, dbRepo := range dbRepos {
-		if !currentSet[dbRepo.FullName] {
-			toDelete = append(toDelete, dbRepo)
-		}
-	}
-
-	if len(toDelete) == 0 {
-		return
-	}
-
-	util.Logger().Info("Cleaning up deleted repositories",
-		zap.Int("count", len(toDelete)),
-	)
-
-	// Parallel: file cleanup + DB deletion
-	var wg sync.WaitGroup
-	var deletedCount int64
-
-	for _, dbRepo := range toDelete {
-		wg.Add(1)
-		go func(repo model.RepoRecord) {
-			defer wg.Done()
-
-			util.Logger().Info("Repository no longer on GitHub; removing",
+	changed := 0
+	for _, repo := range repos {
+		recorded, err := database.RecordRepoMetadata(db, repo)
+		if err != nil {
+			util.Logger().Warn("Failed to record repository metadata",
 				zap.String("repository", repo.FullName),
-			)
-
-			repoName := helper.ExtractRepoName(repo.FullName)
-			helper.CleanupExistingRepo(repoName)
-
-			if err := database.DeleteRepo(db, repo.FullName); err != nil {
-				util.Logger().Warn("Failed to delete repo from DB",
-					zap.String("repository", repo.FullName),
-					zap.Error(err),
-				)
-				return
-			}
-
-			atomic.AddInt64(&deletedCount, 1)
-		}(dbRepo)
-	}
-
-	wg.Wait()
-
-	// Serial: git rm + commit for all deleted repos (git operations must be serial)
-	for _, dbRepo := range toDelete {
-		repoName := helper.ExtractRepoName(dbRepo.FullName)
-		removeCmd := exec.Command("sh", "-c",
-			fmt.Sprintf("cd _Repos && git rm -f '%s.tar.gz' 2>/dev/null || true", repoName))
-		if out, err := removeCmd.CombinedOutput(); err != nil {
-			util.Logger().Warn("Failed to git rm deleted repo archive",
-				zap.String("repository", dbRepo.FullName),
 				zap.Error(err),
-				zap.String("output", string(out)),
 			)
+			continue
 		}
-	}
-
-	commitMsg := helper.SanitizeCommitMessage(fmt.Sprintf("Removed %d deleted repo(s) on %s",
-		deletedCount, time.Now().Format("2006-01-02 Monday 15:04:05")))
-	commitCmd := exec.Command("sh", "-c",
-		fmt.Sprintf("cd _Repos && git diff --staged --quiet || git commit -m '%s' -s", commitMsg))
-	if _, err := commitCmd.CombinedOutput(); err != nil {
-		util.Logger().Warn("Failed to commit deleted repo removals", zap.Error(err))
-	}
-
-	if deletedCount > 0 {
-		util.Logger().Info("Cleaned up deleted repositories",
-			zap.Int64("count", deletedCount),
-		)
-
-		if err := helper.PushBackupRepo("deleted-repos-cleanup"); err != nil {
-			util.Logger().Warn("Failed to push deleted repo cleanup", zap.Error(err))
+		if recorded {
+			changed++
 		}
 	}
+
+	util.Logger().Info("Repository metadata recorded",
+		zap.Int("total", len(repos)),
+		zap.Int("changed", changed),
+	)
 }
 
 func recordFailure(db *sql.DB, repo string, failure error) {
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChangedRefs(t *testing.T) {
+	previous := map[string]string{
+		"HEAD":             "aaa",
+		"refs/heads/main":  "aaa",
+		"refs/heads/old":   "bbb",
+		"refs/tags/v1.0.0": "ccc",
+	}
+
+	tests := []struct {
+		name    string
+		current map[string]string
+		want    []string
+	}{
+		{name: "unchanged", current: previous, want: nil},
+		{
+			name: "tag created without a HEAD change",
+			current: map[string]string{
+				"HEAD": "aaa", "refs/heads/main": "aaa", "refs/heads/old": "bbb",
+				"refs/tags/v1.0.0": "ccc", "refs/tags/v1.1.0": "ddd",
+			},
+			want: []string{"+refs/tags/v1.1.0"},
+		},
+		{
+			name: "moved, created and deleted sorted by ref",
+			current: map[string]string{
+				"HEAD": "eee", "refs/heads/main": "eee", "refs/heads/new": "fff",
+				"refs/tags/v1.0.0": "ccc",
+			},
+			want: []string{"~refs/heads/main", "+refs/heads/new", "-refs/heads/old"},
+		},
+		{
+			name:    "HEAD alone is not reported",
+			current: map[string]string{"HEAD": "bbb", "refs/heads/main": "aaa", "refs/heads/old": "bbb", "refs/tags/v1.0.0": "ccc"},
+			want:    nil,
+		},
+		{
+			name:    "everything deleted",
+			current: map[string]string{},
+			want:    []string{"-refs/heads/main", "-refs/heads/old", "-refs/tags/v1.0.0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := changedRefs(previous, tt.current); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("changedRefs = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
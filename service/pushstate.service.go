@@ -0,0 +1,108 @@
+package service
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/MishraShardendu22/github-backup/controller"
+	"github.com/MishraShardendu22/github-backup/database"
+	"github.com/MishraShardendu22/github-backup/model"
+	"github.com/MishraShardendu22/github-backup/service/helper"
+	"github.com/MishraShardendu22/github-backup/util"
+	"go.uber.org/zap"
+)
+
+// graphQLBatchSize is the number of repositories looked up per GraphQL query
+const graphQLBatchSize = 100
+
+// trustsPushedAt reports whether the API's push time of a repo changes with every push to it. GitHub's
+// pushed_at and a gist's updated_at do; GitLab throttles last_activity_at and wikis have no push time of
+// their own, so those are always checked with ls-remote.
+func trustsPushedAt(repo model.Repo) bool {
+	return repo.WikiOf == "" && (repo.Provider == "" || repo.Provider == model.ProviderGitHub)
+}
+
+// apiPushStates collects the push state of every GitHub repo without running git: pushed_at from the
+// listing, or a batched GraphQL query for repos whose listing did not carry it. Repos missing from the
+// result are checked with ls-remote.
+func apiPushStates(repos []model.Repo, config *model.ConfigModel) map[string]model.RepoPushState {
+	states := make(map[string]model.RepoPushState)
+	lookups := make(map[string][]model.Repo)
+	for _, repo := range repos {
+		if !trustsPushedAt(repo) {
+			continue
+		}
+		if repo.PushedAt != "" {
+			states[repo.FullName] = model.RepoPushState{PushedAt: repo.PushedAt}
+			continue
+		}
+		if _, _, isGist := helper.ParseGistFullName(repo.FullName); !isGist {
+			lookups[repo.Source] = append(lookups[repo.Source], repo)
+		}
+	}
+
+	for sourceName, pending := range lookups {
+		source, ok := sourceFor(config, pending[0])
+		if !ok {
+			continue
+		}
+		token, err := controller.SourceToken(source)
+		if err != nil {
+			util.Logger().Warn("Failed to get token for GraphQL push states; using ls-remote",
+				zap.String("source", sourceName),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		for start := 0; start < len(pending); start += graphQLBatchSize {
+			end := start + graphQLBatchSize
+			if end > len(pending) {
+				end = len(pending)
+			}
+			batch := pending[start:end]
+
+			names := make([]string, 0, len(batch))
+			for _, repo := range batch {
+				names = append(names, source.RemoteName(repo.FullName))
+			}
+			found, err := controller.RepoPushStates(strings.TrimRight(source.APIURL, "/"), token, names)
+			if err != nil {
+				util.Logger().Warn("GraphQL push state lookup failed; using ls-remote",
+					zap.String("source", sourceName),
+					zap.Int("repositories", len(batch)),
+					zap.Error(err),
+				)
+				continue
+			}
+			for i, repo := range batch {
+				if state, ok := found[names[i]]; ok && state.PushedAt != "" {
+					states[repo.FullName] = state
+				}
+			}
+		}
+	}
+
+	return states
+}
+
+// unchangedSincePush reports whether the API shows no push since the repo's refs were last recorded, so
+// the backup can be skipped without ls-remote. Anything short of a matching push time on a repo that is
+// tracked and fully stored is inconclusive. It returns the HEAD recorded with those refs.
+func unchangedSincePush(db *sql.DB, hr repoHashResult, state model.RepoPushState) (string, bool) {
+	if _, found, err := database.GetRepo(db, hr.FullName); err != nil || !found {
+		return "", false
+	}
+	stored, tracked, err := database.GetRepoRefs(db, hr.FullName)
+	if err != nil || !tracked || stored.PushedAt == "" || stored.PushedAt != state.PushedAt {
+		return "", false
+	}
+	head := stored.Refs["HEAD"]
+	if state.DefaultBranchOID != "" && state.DefaultBranchOID != head {
+		return "", false
+	}
+	if !backupArtifactsMatch(hr.RepoPath, hr.Mode) {
+		return "", false
+	}
+	return head, true
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/MishraShardendu22/github-backup/database"
+	"github.com/MishraShardendu22/github-backup/model"
+	"github.com/MishraShardendu22/github-backup/service/helper"
+)
+
+func TestUnchangedSincePush(t *testing.T) {
+	const pushedAt = "2026-10-01T10:00:00Z"
+	const head = "1111111111111111111111111111111111111111"
+
+	tests := []struct {
+		name      string
+		untracked bool
+		stored    string
+		noArchive bool
+		state     model.RepoPushState
+		unchanged bool
+	}{
+		{name: "same push time", stored: pushedAt, state: model.RepoPushState{PushedAt: pushedAt}, unchanged: true},
+		{name: "same default branch commit", stored: pushedAt,
+			state: model.RepoPushState{PushedAt: pushedAt, DefaultBranchOID: head}, unchanged: true},
+		{name: "pushed since", stored: pushedAt, state: model.RepoPushState{PushedAt: "2026-10-02T10:00:00Z"}},
+		{name: "default branch moved", stored: pushedAt,
+			state: model.RepoPushState{PushedAt: pushedAt, DefaultBranchOID: "2222222222222222222222222222222222222222"}},
+		{name: "push time never recorded", stored: "", state: model.RepoPushState{PushedAt: pushedAt}},
+		{name: "not tracked", untracked: true, stored: pushedAt, state: model.RepoPushState{PushedAt: pushedAt}},
+		{name: "archive missing", stored: pushedAt, noArchive: true, state: model.RepoPushState{PushedAt: pushedAt}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newBackupFixture(t)
+			hr := repoHashResult{FullName: "acme/api", RepoPath: helper.RepoPath("acme/api"), Mode: model.BackupModeSnapshot}
+
+			if !tt.untracked {
+				repo := model.Repo{ID: 1, Name: "api", FullName: hr.FullName}
+				if err := database.UpsertRepo(db, repo, repo.Name, "git@github.com:acme/api.git", head); err != nil {
+					t.Fatal(err)
+				}
+			}
+			refs := model.RepoRefs{FullName: hr.FullName, PushedAt: tt.stored, Refs: map[string]string{"HEAD": head, "refs/heads/main": head}}
+			refs.Fingerprint = helper.RefsFingerprint(refs.Refs)
+			if err := database.SaveRepoRefs(db, refs); err != nil {
+				t.Fatal(err)
+			}
+			if !tt.noArchive {
+				archive := filepath.Join("_Repos", helper.ArchiveFileName(hr.RepoPath))
+				if err := os.MkdirAll(filepath.Dir(archive), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(archive, nil, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, unchanged := unchangedSincePush(db, hr, tt.state)
+			if unchanged != tt.unchanged {
+				t.Fatalf("unchanged = %v, want %v", unchanged, tt.unchanged)
+			}
+			if unchanged && got != head {
+				t.Errorf("head = %s, want %s", got, head)
+			}
+		})
+	}
+}
+
+// fakeGraphQL answers push state queries for every aliased repository except those in missing, and records
+// how many repositories each query asked for
+func fakeGraphQL(t *testing.T, missing map[string]bool) (*httptest.Server, func() []int) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var batches []int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/graphql" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		var body struct {
+			Query     string            `json:"query"`
+			Variables map[string]string `json:"variables"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode query: %v", err)
+		}
+
+		data := make(map[string]any)
+		for i := 0; ; i++ {
+			owner, ok := body.Variables[fmt.Sprintf("o%d", i)]
+			if !ok {
+				break
+			}
+			name := owner + "/" + body.Variables[fmt.Sprintf("n%d", i)]
+			if missing[name] {
+				data[fmt.Sprintf("r%d", i)] = nil
+				continue
+			}
+			data[fmt.Sprintf("r%d", i)] = map[string]any{
+				"pushedAt":         "pushed:" + name,
+				"defaultBranchRef": map[string]any{"target": map[string]string{"oid": "oid:" + name}},
+			}
+		}
+		mu.Lock()
+		batches = append(batches, len(data))
+		mu.Unlock()
+
+		json.NewEncoder(w).Encode(map[string]any{"data": data})
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, func() []int {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]int(nil), batches...)
+	}
+}
+
+func TestAPIPushStates(t *testing.T) {
+	srv, batches := fakeGraphQL(t, map[string]bool{"acme/repo-007": true})
+	config := &model.ConfigModel{Sources: []model.Source{
+		{Name: "ghes", Token: "test-token", APIURL: srv.URL + "/api/v3/", Namespace: "ghes"},
+		{Name: "gitlab", Provider: model.ProviderGitLab, Token: "test-token", APIURL: srv.URL},
+	}}
+
+	var repos []model.Repo
+	for i := 0; i < 250; i++ {
+		repos = append(repos, model.Repo{FullName: fmt.Sprintf("ghes/acme/repo-%03d", i), Source: "ghes"})
+	}
+	repos = append(repos,
+		model.Repo{FullName: "ghes/acme/listed", Source: "ghes", PushedAt: "2026-10-01T10:00:00Z"},
+		model.Repo{FullName: "ghes/acme/repo-000.wiki", Source: "ghes", WikiOf: "ghes/acme/repo-000"},
+		model.Repo{FullName: helper.GistFullName("octo", "abc123"), Source: "ghes"},
+		model.Repo{FullName: "group/project", Source: "gitlab", Provider: model.ProviderGitLab},
+	)
+
+	states := apiPushStates(repos, config)
+
+	if got := batches(); fmt.Sprint(got) != "[100 100 50]" {
+		t.Errorf("GraphQL batches = %v, want [100 100 50]", got)
+	}
+	if len(states) != 250 {
+		t.Errorf("states for %d repos, want 250", len(states))
+	}
+	if state := states["ghes/acme/repo-123"]; state.PushedAt != "pushed:acme/repo-123" || state.DefaultBranchOID != "oid:acme/repo-123" {
+		t.Errorf("looked up state = %+v", state)
+	}
+	if state := states["ghes/acme/listed"]; state.PushedAt != "2026-10-01T10:00:00Z" || state.DefaultBranchOID != "" {
+		t.Errorf("listed state = %+v", state)
+	}
+	for _, fullName := range []string{"ghes/acme/repo-007", "ghes/acme/repo-000.wiki", helper.GistFullName("octo", "abc123"), "group/project"} {
+		if state, ok := states[fullName]; ok {
+			t.Errorf("%s: state %+v, want it left to ls-remote", fullName, state)
+		}
+	}
+}
+
+func TestAPIPushStatesLookupFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"Bad credentials"}`, http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	config := &model.ConfigModel{Sources: []model.Source{{Name: "github", Token: "bad-token", APIURL: srv.URL}}}
+	repos := []model.Repo{
+		{FullName: "acme/api", Source: "github"},
+		{FullName: "acme/web", Source: "github", PushedAt: "2026-10-01T10:00:00Z"},
+	}
+
+	states := apiPushStates(repos, config)
+	if len(states) != 1 || !strings.HasPrefix(states["acme/web"].PushedAt, "2026-10-01") {
+		t.Errorf("states = %+v, want only the listed push time", states)
+	}
+}
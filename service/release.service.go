@@ -0,0 +1,259 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MishraShardendu22/github-backup/controller"
+	"github.com/MishraShardendu22/github-backup/database"
+	"github.com/MishraShardendu22/github-backup/model"
+	"github.com/MishraShardendu22/github-backup/service/helper"
+	"github.com/MishraShardendu22/github-backup/service/monitor"
+	"github.com/MishraShardendu22/github-backup/util"
+	"go.uber.org/zap"
+)
+
+// Asset backup statuses recorded in releases.json and the release_assets table
+const (
+	assetStatusStored   = "stored"
+	assetStatusSplit    = "split"
+	assetStatusRecorded = "recorded"
+)
+
+const releasesFileName = "releases.json"
+
+// backupReleases writes every GitHub repo's releases to <owner>/<repo>.releases/releases.json and
+// downloads their assets under assets/<asset-id>/ according to the configured asset policy. Each repo
+// is committed and pushed on its own so a handful of large assets never end up in one giant push.
+func backupReleases(repos []model.Repo, config *model.ConfigModel, db *sql.DB) {
+	if db == nil {
+		return
+	}
+
+	mon := monitor.Get()
+	updated, failed := 0, 0
+	for _, repo := range repos {
+		if repo.GitHubID() == 0 || repo.WikiOf != "" {
+			continue
+		}
+
+		source, ok := sourceFor(config, repo)
+		if !ok {
+			continue
+		}
+
+		staged, err := backupRepoReleases(repo, source, config.ReleaseAssetPolicy, db)
+		if err != nil {
+			failed++
+			util.Logger().Warn("Failed to back up releases",
+				zap.String("repository", repo.FullName),
+				zap.Error(err),
+			)
+			if mon != nil {
+				mon.Log("warn", "Release backup failed: "+err.Error(), repo.FullName)
+			}
+		}
+		if !staged {
+			continue
+		}
+
+		updated++
+		commitMsg := helper.SanitizeCommitMessage(fmt.Sprintf("Releases backed up on %s for the repo %s",
+			time.Now().Format("2006-01-02 Monday 15:04:05"), repo.FullName))
+		pushIfCommitted(commitMsg, "releases "+repo.FullName)
+	}
+
+	util.Logger().Info("Release backup complete",
+		zap.Int("updated", updated),
+		zap.Int("failed", failed),
+		zap.String("asset_policy", config.ReleaseAssetPolicy),
+	)
+	if mon != nil && (updated > 0 || failed > 0) {
+		mon.Log("info", fmt.Sprintf("Release backup: %d repos updated, %d failed", updated, failed), "")
+	}
+}
+
+// backupRepoReleases refreshes one repo's releases directory and stages it. Assets that fail to download
+// are reported in the returned error but do not stop the rest; it reports whether anything changed.
+func backupRepoReleases(repo model.Repo, source model.Source, policy string, db *sql.DB) (bool, error) {
+	token, err := controller.SourceToken(source)
+	if err != nil {
+		return false, fmt.Errorf("token for source %s: %w", source.Name, err)
+	}
+
+	listURL := fmt.Sprintf("%s/repos/%s/releases?per_page=100&page=",
+		strings.TrimRight(source.APIURL, "/"), source.RemoteName(repo.FullName))
+	releases, err := controller.ReleaseController(listURL, token)
+	if err != nil {
+		return false, err
+	}
+
+	releasesDir := helper.ReleasesDirName(helper.RepoPath(repo.FullName))
+	localDir := "_Repos/" + releasesDir
+	if len(releases) == 0 {
+		if _, statErr := os.Stat(localDir); statErr != nil {
+			return false, nil
+		}
+	}
+
+	known, err := database.GetReleaseAssets(db, repo.FullName)
+	if err != nil {
+		return false, fmt.Errorf("read release assets: %w", err)
+	}
+
+	var assetErrs []string
+	seen := make(map[int]bool)
+	for r := range releases {
+		for a := range releases[r].Assets {
+			asset := &releases[r].Assets[a]
+			seen[asset.ID] = true
+
+			backup, err := backupReleaseAsset(*asset, releases[r].ID, repo.FullName, localDir, known, policy, token, db)
+			if err != nil {
+				assetErrs = append(assetErrs, fmt.Sprintf("%s/%s: %v", releases[r].TagName, asset.Name, err))
+				backup = model.AssetBackup{Status: assetStatusRecorded, Reason: "download failed"}
+			}
+			asset.Backup = &backup
+		}
+	}
+
+	// Assets deleted upstream leave the working tree; their last copy stays in the backup repo's history
+	for assetID := range known {
+		if seen[assetID] {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(localDir, assetDir(assetID))); err != nil {
+			return false, err
+		}
+		if err := database.DeleteReleaseAsset(db, assetID); err != nil {
+			return false, fmt.Errorf("delete release asset %d: %w", assetID, err)
+		}
+	}
+
+	if err := os.MkdirAll(localDir, 0o755); err != nil {
+		return false, err
+	}
+	data, err := json.MarshalIndent(releases, "", "  ")
+	if err != nil {
+		return false, err
+	}
+	if err := os.WriteFile(filepath.Join(localDir, releasesFileName), append(data, '\n'), 0o644); err != nil {
+		return false, err
+	}
+
+	if err := helper.StageFiles(releasesDir); err != nil {
+		return false, err
+	}
+	staged := helper.HasStagedChanges()
+
+	if len(assetErrs) > 0 {
+		return staged, fmt.Errorf("%d asset(s) not downloaded: %s", len(assetErrs), strings.Join(assetErrs, "; "))
+	}
+	return staged, nil
+}
+
+// backupReleaseAsset applies the asset policy to one asset. An asset already stored under the same ID,
+// size and update time is not downloaded again.
+func backupReleaseAsset(asset model.ReleaseAsset, releaseID int, fullName string, localDir string,
+	known map[int]model.ReleaseAssetRecord, policy string, token string, db *sql.DB) (model.AssetBackup, error) {
+	if record, ok := known[asset.ID]; ok && record.Size == asset.Size && record.UpdatedAt == asset.UpdatedAt &&
+		record.Backup.Status != assetStatusRecorded && assetFilesPresent(localDir, record.Backup) {
+		return record.Backup, nil
+	}
+
+	dir := assetDir(asset.ID)
+	if err := os.RemoveAll(filepath.Join(localDir, dir)); err != nil {
+		return model.AssetBackup{}, err
+	}
+
+	var backup model.AssetBackup
+	switch {
+	case policy == model.AssetPolicyRecord:
+		backup = model.AssetBackup{Status: assetStatusRecorded, Reason: "asset policy " + policy}
+	case policy == model.AssetPolicyStore && asset.Size > maxGitHubBlobSize:
+		backup = model.AssetBackup{Status: assetStatusRecorded, Reason: "exceeds GitHub blob limit"}
+	default:
+		var err error
+		backup, err = downloadReleaseAsset(asset, localDir, dir, token)
+		if err != nil {
+			return model.AssetBackup{}, err
+		}
+	}
+
+	record := model.ReleaseAssetRecord{
+		AssetID:   asset.ID,
+		ReleaseID: releaseID,
+		FullName:  fullName,
+		Name:      asset.Name,
+		Size:      asset.Size,
+		UpdatedAt: asset.UpdatedAt,
+		Backup:    backup,
+	}
+	if err := database.SaveReleaseAsset(db, record); err != nil {
+		return backup, fmt.Errorf("save release asset: %w", err)
+	}
+
+	return backup, nil
+}
+
+// downloadReleaseAsset fetches the binary into assets/<asset-id>/<name>, splitting it into parts when it is
+// too large for a single git blob
+func downloadReleaseAsset(asset model.ReleaseAsset, localDir string, dir string, token string) (model.AssetBackup, error) {
+	if err := os.MkdirAll(filepath.Join(localDir, dir), 0o755); err != nil {
+		return model.AssetBackup{}, err
+	}
+
+	name := path.Join(dir, filepath.Base(asset.Name))
+	dest := filepath.Join(localDir, name)
+	size, err := controller.DownloadReleaseAsset(asset.URL, token, dest)
+	if err != nil {
+		return model.AssetBackup{}, err
+	}
+
+	sum, err := helper.FileSHA256(dest)
+	if err != nil {
+		return model.AssetBackup{}, err
+	}
+
+	if size <= maxGitHubBlobSize {
+		return model.AssetBackup{Status: assetStatusStored, Path: name, SHA256: sum}, nil
+	}
+
+	parts, err := helper.SplitFile(dest, maxGitHubBlobSize)
+	if err != nil {
+		return model.AssetBackup{}, err
+	}
+	for i, part := range parts {
+		parts[i] = path.Join(dir, filepath.Base(part))
+	}
+
+	return model.AssetBackup{Status: assetStatusSplit, Path: name, Parts: parts, SHA256: sum}, nil
+}
+
+func assetDir(assetID int) string {
+	return path.Join("assets", strconv.Itoa(assetID))
+}
+
+func assetFilesPresent(localDir string, backup model.AssetBackup) bool {
+	files := backup.Parts
+	if backup.Status == assetStatusStored {
+		files = []string{backup.Path}
+	}
+	if len(files) == 0 {
+		return false
+	}
+
+	for _, file := range files {
+		if _, err := os.Stat(filepath.Join(localDir, file)); err != nil {
+			return false
+		}
+	}
+	return true
+}
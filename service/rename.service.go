@@ -0,0 +1,284 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/MishraShardendu22/github-backup/database"
+	"github.com/MishraShardendu22/github-backup/model"
+	"github.com/MishraShardendu22/github-backup/service/helper"
+	"github.com/MishraShardendu22/github-backup/service/monitor"
+	"github.com/MishraShardendu22/github-backup/util"
+	"go.uber.org/zap"
+)
+
+// repoIdentity is a GitHub repository ID together with the forge that issued it. IDs are only unique per
+// forge: github.com and a GitHub Enterprise server can both have a repository 42.
+type repoIdentity struct {
+	forge string
+	id    int
+}
+
+// forgeResolver names the forge a repo lives on: the API URL of its source for discovered repos, and for
+// tracked repos and tombstones the API URL of the source cloning from the host of the recorded clone URL.
+// Without a matching source the clone host itself stands in.
+type forgeResolver struct {
+	config *model.ConfigModel
+	byHost map[string]string
+}
+
+func newForgeResolver(cfg *model.ConfigModel) forgeResolver {
+	byHost := make(map[string]string, len(cfg.Sources))
+	for _, source := range cfg.Sources {
+		host := helper.CloneURLHost(source.CloneHost)
+		if host == "" || source.APIURL == "" {
+			continue
+		}
+		if _, ok := byHost[host]; !ok {
+			byHost[host] = strings.TrimRight(source.APIURL, "/")
+		}
+	}
+	return forgeResolver{config: cfg, byHost: byHost}
+}
+
+func (f forgeResolver) discovered(repo model.Repo) repoIdentity {
+	if source, ok := sourceFor(f.config, repo); ok && source.APIURL != "" {
+		return repoIdentity{forge: strings.TrimRight(source.APIURL, "/"), id: repo.GitHubID()}
+	}
+	return repoIdentity{forge: helper.CloneURLHost(cloneURLFor(f.config, repo)), id: repo.GitHubID()}
+}
+
+func (f forgeResolver) recorded(cloneURL string, githubID int) repoIdentity {
+	host := helper.CloneURLHost(cloneURL)
+	if forge, ok := f.byHost[host]; ok {
+		return repoIdentity{forge: forge, id: githubID}
+	}
+	return repoIdentity{forge: host, id: githubID}
+}
+
+// detectRenamedRepos matches tracked repos to discovered ones by GitHub repository ID on the same forge. A
+// repo whose full name changed (rename, or transfer to another owner) keeps its archive, history and hash
+// instead of being deleted and cloned again under the new name. A tracked repo whose old name is still
+// discovered is left alone, so a name reused by another repository never has its archive taken over.
+func detectRenamedRepos(currentRepos []model.Repo, config *model.ConfigModel, db *sql.DB) {
+	if db == nil {
+		return
+	}
+
+	present := make(map[string]bool, len(currentRepos))
+	for _, repo := range currentRepos {
+		present[repo.FullName] = true
+	}
+	forges := newForgeResolver(config)
+
+	dbRepos, err := database.GetAllReposFromDB(db)
+	if err != nil {
+		util.Logger().Warn("Failed to fetch repos from DB for rename detection", zap.Error(err))
+		return
+	}
+
+	tracked := make(map[string]bool, len(dbRepos))
+	byID := make(map[repoIdentity]model.RepoRecord, len(dbRepos))
+	for _, dbRepo := range dbRepos {
+		tracked[dbRepo.FullName] = true
+		if dbRepo.GitHubID != 0 {
+			byID[forges.recorded(dbRepo.CloneURL, dbRepo.GitHubID)] = dbRepo
+		}
+	}
+
+	renamed := 0
+	for _, repo := range currentRepos {
+		if repo.GitHubID() == 0 || tracked[repo.FullName] {
+			continue
+		}
+
+		old, ok := byID[forges.discovered(repo)]
+		if !ok || old.FullName == repo.FullName || present[old.FullName] {
+			continue
+		}
+
+		if err := renameTrackedRepo(db, old.FullName, repo.FullName); err != nil {
+			util.Logger().Warn("Failed to rename repository",
+				zap.String("from", old.FullName),
+				zap.String("to", repo.FullName),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		// Wikis carry no repository ID of their own; they follow their parent so their history stays continuous
+		oldWiki, newWiki := old.FullName+wikiSuffix, repo.FullName+wikiSuffix
+		if tracked[oldWiki] && !tracked[newWiki] {
+			if err := renameTrackedRepo(db, oldWiki, newWiki); err != nil {
+				util.Logger().Warn("Failed to rename wiki of renamed repository",
+					zap.String("from", oldWiki),
+					zap.String("to", newWiki),
+					zap.Error(err),
+				)
+			} else {
+				tracked[newWiki] = true
+				logRename(oldWiki, newWiki)
+			}
+		}
+
+		tracked[repo.FullName] = true
+		renamed++
+		logRename(old.FullName, repo.FullName)
+	}
+
+	renamed += detectRenamedTombstones(currentRepos, present, tracked, forges, db)
+
+	if renamed == 0 {
+		return
+	}
+
+	commitMsg := helper.SanitizeCommitMessage(fmt.Sprintf("Renamed %d repo(s) on %s",
+		renamed, time.Now().Format("2006-01-02 Monday 15:04:05")))
+	pushIfCommitted(commitMsg, "repo-renames")
+}
+
+// detectRenamedTombstones handles repos that went missing and later came back under a new name;
+// the tombstone follows the new name so restoreTombstonedRepos can bring the archive back
+func detectRenamedTombstones(currentRepos []model.Repo, present, tracked map[string]bool, forges forgeResolver, db *sql.DB) int {
+	tombstones, err := database.GetAllTombstones(db)
+	if err != nil {
+		util.Logger().Warn("Failed to fetch tombstones for rename detection", zap.Error(err))
+		return 0
+	}
+
+	byID := make(map[repoIdentity]model.Tombstone, len(tombstones))
+	byName := make(map[string]model.Tombstone, len(tombstones))
+	for _, tombstone := range tombstones {
+		tracked[tombstone.FullName] = true
+		byName[tombstone.FullName] = tombstone
+		if tombstone.GitHubID != 0 {
+			byID[forges.recorded(tombstone.CloneURL, tombstone.GitHubID)] = tombstone
+		}
+	}
+
+	renamed := 0
+	for _, repo := range currentRepos {
+		if repo.GitHubID() == 0 || tracked[repo.FullName] {
+			continue
+		}
+
+		old, ok := byID[forges.discovered(repo)]
+		if !ok || old.FullName == repo.FullName || present[old.FullName] {
+			continue
+		}
+
+		newName := helper.ExtractRepoName(repo.FullName)
+		if err := database.RenameTombstone(db, old.FullName, repo.FullName, newName); err != nil {
+			util.Logger().Warn("Failed to rename tombstone in DB",
+				zap.String("from", old.FullName),
+				zap.String("to", repo.FullName),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		oldWiki, newWiki := old.FullName+wikiSuffix, repo.FullName+wikiSuffix
+		if _, ok := byName[oldWiki]; ok && !tracked[newWiki] {
+			if err := database.RenameTombstone(db, oldWiki, newWiki, helper.ExtractRepoName(newWiki)); err != nil {
+				util.Logger().Warn("Failed to rename wiki tombstone in DB",
+					zap.String("from", oldWiki),
+					zap.String("to", newWiki),
+					zap.Error(err),
+				)
+			} else {
+				tracked[newWiki] = true
+				logRename(oldWiki, newWiki)
+			}
+		}
+
+		tracked[repo.FullName] = true
+		renamed++
+		logRename(old.FullName, repo.FullName)
+	}
+
+	return renamed
+}
+
+// renameTrackedRepo moves a tracked repo's archive and sidecars to its new name and renames every
+// SQLite row keyed by its full name. Only the archive move and the repos row are fatal.
+func renameTrackedRepo(db *sql.DB, oldFullName, newFullName string) error {
+	if err := renameRepoArchive(oldFullName, newFullName); err != nil {
+		return fmt.Errorf("move archive: %w", err)
+	}
+
+	if err := database.RenameRepo(db, oldFullName, newFullName, helper.ExtractRepoName(newFullName)); err != nil {
+		return fmt.Errorf("rename in DB: %w", err)
+	}
+
+	if err := database.RenameExportCursor(db, oldFullName, newFullName); err != nil {
+		util.Logger().Warn("Failed to rename export cursor in DB",
+			zap.String("from", oldFullName),
+			zap.String("to", newFullName),
+			zap.Error(err),
+		)
+	}
+	if err := database.RenameReleaseAssets(db, oldFullName, newFullName); err != nil {
+		util.Logger().Warn("Failed to rename release assets in DB",
+			zap.String("from", oldFullName),
+			zap.String("to", newFullName),
+			zap.Error(err),
+		)
+	}
+	if err := database.RenameMirrorState(db, oldFullName, newFullName); err != nil {
+		util.Logger().Warn("Failed to rename mirror state in DB",
+			zap.String("from", oldFullName),
+			zap.String("to", newFullName),
+			zap.Error(err),
+		)
+	}
+	if err := database.RenameRepoRefs(db, oldFullName, newFullName); err != nil {
+		util.Logger().Warn("Failed to rename repo refs in DB",
+			zap.String("from", oldFullName),
+			zap.String("to", newFullName),
+			zap.Error(err),
+		)
+	}
+
+	return nil
+}
+
+func renameRepoArchive(oldFullName, newFullName string) error {
+	oldPath := helper.RepoPath(oldFullName)
+	newPath := helper.RepoPath(newFullName)
+	if oldPath == newPath {
+		return nil
+	}
+
+	if _, err := helper.MoveTrackedFile(helper.ArchiveFileName(oldPath), helper.ArchiveFileName(newPath)); err != nil {
+		return err
+	}
+
+	newSidecars := helper.ArchiveSidecars(newPath)
+	for i, sidecar := range helper.ArchiveSidecars(oldPath) {
+		if _, err := helper.MoveTrackedFile(sidecar, newSidecars[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func logRename(oldFullName, newFullName string) {
+	kind := "renamed"
+	oldOwner, _, _ := strings.Cut(oldFullName, "/")
+	newOwner, _, _ := strings.Cut(newFullName, "/")
+	if !strings.EqualFold(oldOwner, newOwner) {
+		kind = "transferred"
+	}
+
+	util.Logger().Info("Repository "+kind,
+		zap.String("from", oldFullName),
+		zap.String("to", newFullName),
+	)
+
+	if mon := monitor.Get(); mon != nil {
+		mon.Log("info", fmt.Sprintf("Repository %s from %s to %s", kind, oldFullName, newFullName), newFullName)
+	}
+}
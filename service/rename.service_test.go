@@ -0,0 +1,102 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/MishraShardendu22/github-backup/database"
+	"github.com/MishraShardendu22/github-backup/model"
+	"github.com/MishraShardendu22/github-backup/service/helper"
+)
+
+// renameConfig has a github.com source and a GitHub Enterprise source, whose repository IDs overlap
+func renameConfig() *model.ConfigModel {
+	return &model.ConfigModel{Sources: []model.Source{
+		{Name: "github", APIURL: "https://api.github.com", CloneHost: "git@github.com"},
+		{Name: "ghes", APIURL: "https://ghe.example.com/api/v3/", CloneHost: "ssh://git@ghe.example.com:2222", Namespace: "ghes"},
+	}}
+}
+
+// discoveredFrom is a discovery result for one source; IDs are the ones trackRepos hands out from 100 on
+func discoveredFrom(source string, ids map[string]int) []model.Repo {
+	var repos []model.Repo
+	for fullName, id := range ids {
+		repos = append(repos, model.Repo{ID: id, Name: helper.ExtractRepoName(fullName), FullName: fullName, Source: source})
+	}
+	return repos
+}
+
+func TestDetectRenamedRepos(t *testing.T) {
+	tests := []struct {
+		name       string
+		discovered []model.Repo
+		tracked    string
+		moved      string
+	}{
+		{
+			name:       "renamed on the same forge",
+			discovered: discoveredFrom("github", map[string]int{"acme/api-v2": 100, "acme/web": 101}),
+			tracked:    "acme/api-v2 acme/web",
+			moved:      "acme/api-v2",
+		},
+		{
+			name: "same ID on another forge",
+			discovered: append(discoveredFrom("github", map[string]int{"acme/web": 101}),
+				discoveredFrom("ghes", map[string]int{"ghes/acme/tools": 100})...),
+			tracked: "acme/api acme/web",
+		},
+		{
+			name: "old name still discovered",
+			// acme/api was renamed to acme/api-v2 and a new repository took over its name
+			discovered: discoveredFrom("github", map[string]int{"acme/api": 500, "acme/api-v2": 100, "acme/web": 101}),
+			tracked:    "acme/api acme/web",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newBackupFixture(t)
+			trackRepos(t, db, "acme/api", "acme/web")
+
+			detectRenamedRepos(tt.discovered, renameConfig(), db)
+
+			if names := trackedNames(t, db); strings.Join(names, " ") != tt.tracked {
+				t.Errorf("tracked = %v, want %s", names, tt.tracked)
+			}
+			oldArchive, _ := archivePaths("acme/api")
+			if tt.moved == "" {
+				if !inRepos(oldArchive) {
+					t.Error("archive of a repo that was not renamed moved")
+				}
+				return
+			}
+			newArchive, _ := archivePaths(tt.moved)
+			if inRepos(oldArchive) || !inRepos(newArchive) {
+				t.Errorf("archive not moved to %s", newArchive)
+			}
+		})
+	}
+}
+
+func TestDetectRenamedTombstonesPerForge(t *testing.T) {
+	db := newBackupFixture(t)
+	trackRepos(t, db, "acme/api", "acme/web")
+	config := renameConfig()
+
+	// acme/api goes missing on github.com, then an Enterprise repository with the same ID shows up
+	processDeletedRepos(discoveredFrom("github", map[string]int{"acme/web": 101}), config, db)
+	detectRenamedRepos(append(discoveredFrom("github", map[string]int{"acme/web": 101}),
+		discoveredFrom("ghes", map[string]int{"ghes/acme/tools": 100})...), config, db)
+
+	tombstones, err := database.GetAllTombstones(db)
+	if err != nil || len(tombstones) != 1 || tombstones[0].FullName != "acme/api" {
+		t.Fatalf("tombstones = %+v, %v, want acme/api untouched", tombstones, err)
+	}
+
+	// The same ID back on github.com under a new name is a rename
+	detectRenamedRepos(discoveredFrom("github", map[string]int{"acme/api-v2": 100, "acme/web": 101}), config, db)
+
+	tombstones, err = database.GetAllTombstones(db)
+	if err != nil || len(tombstones) != 1 || tombstones[0].FullName != "acme/api-v2" {
+		t.Errorf("tombstones = %+v, %v, want acme/api renamed to acme/api-v2", tombstones, err)
+	}
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/MishraShardendu22/github-backup/model"
+	"github.com/MishraShardendu22/github-backup/util"
+	"go.uber.org/zap"
+)
+
+// wikiSuffix turns owner/repo into owner/repo.wiki, the name forges give a repo's wiki remote
+const wikiSuffix = ".wiki"
+
+// appendWikis adds a <repo>.wiki entry for every repo that reports a wiki, so wikis go through the
+// same hash check, clone, archive and push phases and get their own SQLite row and backup result.
+// Many repos report has_wiki without ever creating a page; those are skipped silently in the hash check.
+func appendWikis(repos []model.Repo) []model.Repo {
+	withWikis := make([]model.Repo, 0, len(repos))
+	wikis := 0
+	for _, repo := range repos {
+		withWikis = append(withWikis, repo)
+		if repo.HasWiki && repo.WikiOf == "" {
+			withWikis = append(withWikis, wikiRepo(repo))
+			wikis++
+		}
+	}
+
+	if wikis > 0 {
+		util.Logger().Info("Wikis added to backup",
+			zap.Int("count", wikis),
+		)
+	}
+
+	return withWikis
+}
+
+func wikiRepo(parent model.Repo) model.Repo {
+	wiki := parent
+	wiki.ID = 0
+	wiki.HasWiki = false
+	wiki.WikiOf = parent.FullName
+	wiki.Name = parent.Name + wikiSuffix
+	wiki.FullName = parent.FullName + wikiSuffix
+	wiki.Topics = nil
+	if parent.SSHURL != "" {
+		wiki.SSHURL = strings.TrimSuffix(parent.SSHURL, ".git") + wikiSuffix + ".git"
+	}
+	if parent.CloneURL != "" {
+		wiki.CloneURL = strings.TrimSuffix(parent.CloneURL, ".git") + wikiSuffix + ".git"
+	}
+
+	return wiki
+}
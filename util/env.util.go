@@ -1,6 +1,10 @@
 package util
 
-import "os"
+import (
+	"os"
+	"strconv"
+	"strings"
+)
 
 func GetEnv(Expected string, Default string) string {
 	secret := os.Getenv(Expected)
@@ -11,3 +15,43 @@ func GetEnv(Expected string, Default string) string {
 
 	return secret
 }
+
+// GetEnvList splits a comma separated variable, dropping empty entries
+func GetEnvList(Expected string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(Expected), ",") {
+		value = strings.TrimSpace(value)
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
+
+func GetEnvBool(Expected string, Default bool) bool {
+	value, err := strconv.ParseBool(strings.TrimSpace(os.Getenv(Expected)))
+	if err != nil {
+		return Default
+	}
+
+	return value
+}
+
+func GetEnvInt(Expected string, Default int) int {
+	value, err := strconv.Atoi(strings.TrimSpace(os.Getenv(Expected)))
+	if err != nil {
+		return Default
+	}
+
+	return value
+}
+
+func GetEnvFloat(Expected string, Default float64) float64 {
+	value, err := strconv.ParseFloat(strings.TrimSpace(os.Getenv(Expected)), 64)
+	if err != nil {
+		return Default
+	}
+
+	return value
+}